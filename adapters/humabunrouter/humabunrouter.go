@@ -45,6 +45,10 @@ func (c *bunContext) Host() string {
 	return c.r.Host
 }
 
+func (c *bunContext) RemoteAddr() string {
+	return c.r.RemoteAddr
+}
+
 func (c *bunContext) URL() url.URL {
 	return *c.r.URL
 }
@@ -131,6 +135,10 @@ func (c *bunCompatContext) Host() string {
 	return c.r.Host
 }
 
+func (c *bunCompatContext) RemoteAddr() string {
+	return c.r.RemoteAddr
+}
+
 func (c *bunCompatContext) URL() url.URL {
 	return *c.r.URL
 }
@@ -43,6 +43,10 @@ func (c *fiberCtx) Host() string {
 	return c.orig.Hostname()
 }
 
+func (c *fiberCtx) RemoteAddr() string {
+	return c.orig.Context().RemoteAddr().String()
+}
+
 func (c *fiberCtx) URL() url.URL {
 	u, _ := url.Parse(string(c.orig.Request().RequestURI()))
 	return *u
@@ -149,6 +153,23 @@ func (a *fiberAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	io.Copy(w, resp.Body)
 }
 
+// RouteErrorHandler returns a Fiber handler which sends consistent errors for
+// requests which don't match any route: a 405 Method Not Allowed error with an
+// `Allow` header for registered paths, otherwise a 404 Not Found error. It
+// must be added with `app.Use` after all routes are registered, as routes
+// added after it are never reached.
+//
+//	app := fiber.New()
+//	api := humafiber.New(app, huma.DefaultConfig("My API", "1.0.0"))
+//	huma.Get(api, "/things/{id}", getThing)
+//	app.Use(humafiber.RouteErrorHandler(api))
+func RouteErrorHandler(api huma.API) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		huma.WriteRouteError(api, &fiberCtx{orig: c})
+		return nil
+	}
+}
+
 func New(r *fiber.App, config huma.Config) huma.API {
 	return huma.NewAPI(config, &fiberAdapter{tester: r, router: r})
 }
@@ -2,11 +2,14 @@ package humafiber
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
 )
 
 func BenchmarkHumaFiber(b *testing.B) {
@@ -59,3 +62,47 @@ func BenchmarkNotHuma(b *testing.B) {
 		r.Test(req)
 	}
 }
+
+func TestRouteErrorHandler(t *testing.T) {
+	app := fiber.New()
+	api := New(app, huma.DefaultConfig("Test API", "1.0.0"))
+
+	huma.Get(api, "/things/{id}", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*struct{}, error) {
+		return nil, huma.Error404NotFound("thing not found")
+	})
+	app.Delete("/things/raw", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusAccepted)
+	})
+	app.Use(RouteErrorHandler(api))
+
+	do := func(method, path string) *http.Response {
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodPatch, "/things/foo")
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "GET, HEAD", resp.Header.Get("Allow"))
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "method PATCH not allowed")
+
+	resp = do(http.MethodGet, "/unknown")
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, string(body), "no operation found for path /unknown")
+
+	// Operations and routes registered directly on the router still work.
+	resp = do(http.MethodGet, "/things/foo")
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, string(body), "thing not found")
+
+	resp = do(http.MethodDelete, "/things/raw")
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
@@ -126,6 +126,11 @@ func (c subContext) Context() context.Context {
 	return c.override
 }
 
+// Unwrap returns the wrapped context, see `huma.GetRemoteAddr`.
+func (c subContext) Unwrap() Context {
+	return c.humaContext
+}
+
 // WithContext returns a new `huma.Context` with the underlying `context.Context`
 // replaced with the given one. This is useful for middleware that needs to
 // modify the request context.
@@ -140,6 +145,36 @@ func WithValue(ctx Context, key, value any) Context {
 	return WithContext(ctx, context.WithValue(ctx.Context(), key, value))
 }
 
+// RemoteAddrer is implemented by contexts which know the network address of
+// the client connection, like those of the built-in router adapters.
+type RemoteAddrer interface {
+	// RemoteAddr returns the network address of the client, usually
+	// `ip:port`.
+	RemoteAddr() string
+}
+
+// GetRemoteAddr returns the network address of the client connection which
+// sent the request, usually `ip:port`, or an empty string if the router
+// adapter doesn't provide it. Behind a reverse proxy or load balancer this is
+// the address of the proxy rather than the client.
+//
+// Middleware which wraps the context hides the adapter's `RemoteAddrer`
+// implementation, so wrappers should implement `Unwrap() huma.Context` to
+// return the context they wrap, like those created by `huma.WithContext`.
+func GetRemoteAddr(ctx Context) string {
+	for ctx != nil {
+		if ra, ok := ctx.(RemoteAddrer); ok {
+			return ra.RemoteAddr()
+		}
+		u, ok := ctx.(interface{ Unwrap() Context })
+		if !ok {
+			break
+		}
+		ctx = u.Unwrap()
+	}
+	return ""
+}
+
 // Transformer is a function that can modify a response body before it is
 // serialized. The `status` is the HTTP status code for the response and `v` is
 // the value to be serialized. The return value is the new value to be
@@ -178,6 +213,15 @@ type Config struct {
 	// chosen from the keys of `Formats`.
 	DefaultFormat string
 
+	// RequestDecoders decompress request bodies by their `Content-Encoding`,
+	// e.g. `gzip`, and are documented as a `Content-Encoding` header param.
+	// Requests using other encodings are rejected with a 415 Unsupported
+	// Media Type error. Body size limits apply to the decompressed body to
+	// guard against decompression bombs. If nil, which is the default, request
+	// bodies are passed through as sent regardless of their encoding. Set it
+	// to `DefaultRequestDecoders` to support `gzip` and `deflate`.
+	RequestDecoders map[string]RequestDecoder
+
 	// Transformers are a way to modify a response body before it is serialized.
 	Transformers []Transformer
 
@@ -186,6 +230,62 @@ type Config struct {
 	// for example if you need access to the path settings that may be changed
 	// by the user after the defaults have been set.
 	CreateHooks []func(Config) Config
+
+	// ResponseValidation enables validation of response bodies and headers
+	// against the documented response schemas for all operations. This is
+	// meant for development, staging, and tests as it is slow and may reject
+	// otherwise working responses. It can be overridden per operation.
+	ResponseValidation ResponseValidationMode
+
+	// OnResponseValidationError is called with the errors found when response
+	// validation is enabled and a response does not match its documented
+	// schema. If unset, errors are logged instead.
+	OnResponseValidationError func(ctx Context, status int, errs []*ErrorDetail)
+
+	// AutoHead registers a `HEAD` operation for each `GET` operation, which
+	// runs the `GET` handler and sends its status and headers without the
+	// body. `HEAD` operations are documented in the OpenAPI as well.
+	AutoHead bool
+
+	// StrictContentNegotiation rejects requests with a `Content-Type` which is
+	// not declared by the operation with a 415 Unsupported Media Type error,
+	// and requests with an `Accept` header which can't be satisfied with a 406
+	// Not Acceptable error instead of falling back to the default format. Both
+	// errors are documented for the operations they apply to.
+	StrictContentNegotiation bool
+
+	// Authenticators verify credentials for the security schemes in the
+	// OpenAPI components, keyed by scheme name. When set, the security
+	// requirements of each operation are enforced and requests which don't
+	// satisfy them get a 401 Unauthorized error, or a 403 Forbidden error if
+	// the principal is missing required scopes. Operations which use a scheme
+	// without an authenticator panic at registration.
+	Authenticators map[string]Authenticator
+
+	// Metrics receives request counts, durations, body sizes, and outcomes
+	// for each operation. See the `prometheus` package for a collector which
+	// serves them in the Prometheus text format.
+	Metrics MetricsCollector
+
+	// Tracer starts spans for each request and each phase of handling it,
+	// e.g. parsing parameters, calling the handler, and marshaling the
+	// response. See `humatest.NewTraceRecorder` for an in-memory tracer.
+	Tracer Tracer
+
+	// CORS enables Cross-Origin Resource Sharing. CORS headers are added to
+	// responses for allowed origins, and preflight `OPTIONS` requests are
+	// answered for each documented path with the methods registered for it.
+	CORS *CORS
+
+	// RecoverPanics catches panics in middleware, handlers, resolvers, and
+	// transformers while handling requests for operations, and writes a 500
+	// Internal Server Error response instead of leaving it to the router. If
+	// the response was already started then it is left as is.
+	RecoverPanics bool
+
+	// OnPanic is called for each panic caught when `RecoverPanics` is set,
+	// e.g. to log it with the stack trace or report it to an error tracker.
+	OnPanic func(ctx Context, err *PanicError)
 }
 
 // API represents a Huma API wrapping a specific router.
@@ -231,6 +331,23 @@ type API interface {
 	Middlewares() Middlewares
 }
 
+// ConfigProvider is an optional interface for an `API` which exposes the
+// configuration it was created with. APIs created with `huma.NewAPI` implement
+// it. Features enabled through the config, like CORS or response validation,
+// are disabled for APIs which don't.
+type ConfigProvider interface {
+	Config() Config
+}
+
+// configOf returns the configuration of the API, or an empty configuration
+// if it does not provide one.
+func configOf(api API) Config {
+	if p, ok := api.(ConfigProvider); ok {
+		return p.Config()
+	}
+	return Config{}
+}
+
 // Format represents a request / response format. It is used to marshal and
 // unmarshal data.
 type Format struct {
@@ -254,6 +371,10 @@ func (a *api) Adapter() Adapter {
 	return a.adapter
 }
 
+func (a *api) Config() Config {
+	return a.config
+}
+
 func (a *api) OpenAPI() *OpenAPI {
 	return a.config.OpenAPI
 }
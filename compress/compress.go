@@ -0,0 +1,362 @@
+// Package compress provides middleware to compress responses based on the
+// client's `Accept-Encoding` header. It works with any router adapter as it
+// wraps the `huma.Context`, and supports streaming responses like Server Sent
+// Events by compressing and flushing each chunk as it is written.
+//
+//	api.UseMiddleware(compress.New(compress.Options{}))
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// DefaultMinSize is the default minimum response size in bytes before it gets
+// compressed. Smaller responses are sent as-is since compression would save
+// little or even make them larger.
+const DefaultMinSize = 1024
+
+// DefaultContentTypes are the default media types which get compressed. A
+// `*` matches any characters, so `text/*` matches all text types and
+// `application/*+json` matches structured JSON types like
+// `application/problem+json`.
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/x-ndjson",
+	"application/jsonl",
+	"application/yaml",
+	"application/xml",
+	"application/*+xml",
+	"application/javascript",
+	"image/svg+xml",
+}
+
+// Writer is a compressing writer created by an `Encoder`. `Flush` must write
+// any buffered data so that streaming responses reach the client, and `Close`
+// must write any remaining data and footers.
+type Writer interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// Encoder creates a compressing writer for a content encoding like `gzip`.
+type Encoder func(w io.Writer) (Writer, error)
+
+// DefaultEncoders is a map of the default encoders, keyed by the content
+// encoding name used in the `Accept-Encoding` and `Content-Encoding` headers.
+// Add entries to support other encodings like `br` or `zstd`:
+//
+//	encoders := map[string]compress.Encoder{
+//		"gzip": compress.DefaultEncoders["gzip"],
+//		"br": func(w io.Writer) (compress.Writer, error) {
+//			return brotli.NewWriter(w), nil
+//		},
+//	}
+var DefaultEncoders = map[string]Encoder{
+	"gzip": func(w io.Writer) (Writer, error) {
+		return gzip.NewWriter(w), nil
+	},
+	"deflate": func(w io.Writer) (Writer, error) {
+		// HTTP `deflate` is the zlib format rather than raw deflate.
+		return zlib.NewWriter(w), nil
+	},
+}
+
+// Options configure response compression.
+type Options struct {
+	// MinSize is the minimum response size in bytes before it is compressed.
+	// Responses are buffered until this size is reached or they are flushed.
+	// If unset, defaults to `DefaultMinSize`. Use a negative value to compress
+	// all responses regardless of size.
+	MinSize int
+
+	// ContentTypes are the media types which get compressed, where `*`
+	// matches any characters. If unset, defaults to `DefaultContentTypes`.
+	ContentTypes []string
+
+	// Encoders are the supported content encodings. If the client prefers
+	// several equally, the encoding which sorts first by name is used. If
+	// unset, defaults to `DefaultEncoders`.
+	Encoders map[string]Encoder
+}
+
+// New returns a middleware which compresses responses with the encoding the
+// client prefers from its `Accept-Encoding` header. Compressed responses have
+// their `Content-Length` removed, and `Vary: Accept-Encoding` is set for all
+// responses with a compressible content type so caches store each variant.
+func New(opts Options) func(ctx huma.Context, next func(huma.Context)) {
+	if opts.MinSize == 0 {
+		opts.MinSize = DefaultMinSize
+	}
+	if opts.ContentTypes == nil {
+		opts.ContentTypes = DefaultContentTypes
+	}
+	if opts.Encoders == nil {
+		opts.Encoders = DefaultEncoders
+	}
+	names := make([]string, 0, len(opts.Encoders))
+	for name := range opts.Encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return func(ctx huma.Context, next func(huma.Context)) {
+		if ctx.Method() == http.MethodHead {
+			next(ctx)
+			return
+		}
+
+		c := &compressContext{
+			humaContext: ctx,
+			opts:        &opts,
+		}
+		c.encoding, c.identity = selectEncoding(ctx.Header("Accept-Encoding"), names)
+		next(c)
+		c.finish()
+	}
+}
+
+// selectEncoding returns the supported encoding with the highest quality
+// value in the `Accept-Encoding` header, or an empty string if none are
+// acceptable, and whether an uncompressed response is acceptable. A `*`
+// matches any encoding not listed explicitly, see RFC 9110 section 12.5.3.
+// Unlike most headers, a quality of zero means not acceptable. The names must
+// be sorted.
+func selectEncoding(header string, names []string) (string, bool) {
+	if strings.TrimSpace(header) == "" {
+		return "", true
+	}
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+		qualities[name] = q
+	}
+	quality := func(name string, fallback float64) float64 {
+		if q, ok := qualities[name]; ok {
+			return q
+		}
+		if q, ok := qualities["*"]; ok {
+			return q
+		}
+		return fallback
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, n := range names {
+		if q := quality(n, 0); q > bestQ {
+			best, bestQ = n, q
+		}
+	}
+	// Uncompressed responses are acceptable unless excluded.
+	return best, quality("identity", 1) > 0
+}
+
+// matchContentType returns whether the content type matches any of the
+// patterns, where `*` matches any characters.
+func matchContentType(contentType string, patterns []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if mediaType == pattern {
+				return true
+			}
+			continue
+		}
+		if len(mediaType) >= len(prefix)+len(suffix) && strings.HasPrefix(mediaType, prefix) && strings.HasSuffix(mediaType, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// humaContext is embedded in the wrapper without its name conflicting with
+// the `Context()` method.
+type humaContext huma.Context
+
+// compressContext holds back the response status and headers until enough
+// of the body has been written to decide whether to compress it.
+type compressContext struct {
+	humaContext
+	opts     *Options
+	encoding string
+
+	// identity is whether the client accepts uncompressed responses. If not,
+	// responses are compressed regardless of their size.
+	identity bool
+
+	status        int
+	contentType   string
+	contentLength string
+	encoded       bool
+
+	decided bool
+	buf     bytes.Buffer
+	writer  io.Writer
+	encoder Writer
+}
+
+func (c *compressContext) SetStatus(code int) {
+	c.status = code
+	if c.decided {
+		c.humaContext.SetStatus(code)
+	}
+}
+
+// Unwrap returns the wrapped context, see `huma.GetRemoteAddr`.
+func (c *compressContext) Unwrap() huma.Context {
+	return c.humaContext
+}
+
+func (c *compressContext) Status() int {
+	if c.status != 0 {
+		return c.status
+	}
+	return c.humaContext.Status()
+}
+
+func (c *compressContext) SetHeader(name, value string) {
+	if c.track(name, value) {
+		return
+	}
+	c.humaContext.SetHeader(name, value)
+}
+
+func (c *compressContext) AppendHeader(name, value string) {
+	if c.track(name, value) {
+		return
+	}
+	c.humaContext.AppendHeader(name, value)
+}
+
+// track records headers which affect compression. Returns true if the header
+// is held back until the compression decision is made.
+func (c *compressContext) track(name, value string) bool {
+	switch strings.ToLower(name) {
+	case "content-type":
+		c.contentType = value
+	case "content-encoding":
+		c.encoded = true
+	case "content-length":
+		if !c.decided {
+			c.contentLength = value
+			return true
+		}
+	}
+	return false
+}
+
+func (c *compressContext) BodyWriter() io.Writer {
+	return (*compressWriter)(c)
+}
+
+// decide chooses whether to compress the response, then writes the held back
+// status and headers. Small responses which are not being streamed are only
+// compressed if they reach the minimum size.
+func (c *compressContext) decide(size int, streaming bool) {
+	c.decided = true
+	c.writer = c.humaContext.BodyWriter()
+
+	compressible := !c.encoded && c.status != http.StatusNoContent && c.status != http.StatusNotModified && matchContentType(c.contentType, c.opts.ContentTypes)
+	if compressible {
+		c.humaContext.AppendHeader("Vary", "Accept-Encoding")
+	}
+
+	if compressible && c.encoding != "" && (streaming || size >= c.opts.MinSize || !c.identity) {
+		if enc, err := c.opts.Encoders[c.encoding](c.writer); err == nil {
+			c.encoder = enc
+			c.writer = enc
+			c.contentLength = ""
+			c.humaContext.SetHeader("Content-Encoding", c.encoding)
+		}
+	}
+
+	if c.contentLength != "" {
+		c.humaContext.SetHeader("Content-Length", c.contentLength)
+	}
+	if c.status != 0 {
+		c.humaContext.SetStatus(c.status)
+	}
+}
+
+// finish writes any buffered body and closes the encoder once the handler is
+// done with the response.
+func (c *compressContext) finish() {
+	if !c.decided {
+		if c.status == 0 && c.buf.Len() == 0 {
+			// Nothing was written, so leave the response to the router.
+			return
+		}
+		c.decide(c.buf.Len(), false)
+		if c.buf.Len() > 0 {
+			c.writer.Write(c.buf.Bytes())
+		}
+	}
+	if c.encoder != nil {
+		c.encoder.Close()
+	}
+}
+
+// compressWriter is the response body writer for a `compressContext`.
+type compressWriter compressContext
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	c := (*compressContext)(w)
+	if c.decided {
+		return c.writer.Write(p)
+	}
+
+	c.buf.Write(p)
+	if c.buf.Len() >= c.opts.MinSize {
+		c.decide(c.buf.Len(), false)
+		_, err := c.writer.Write(c.buf.Bytes())
+		c.buf.Reset()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends any buffered data to the client, so streaming responses are
+// compressed chunk by chunk rather than waiting for the minimum size.
+func (w *compressWriter) Flush() {
+	c := (*compressContext)(w)
+	if !c.decided {
+		c.decide(c.buf.Len(), true)
+		if c.buf.Len() > 0 {
+			c.writer.Write(c.buf.Bytes())
+			c.buf.Reset()
+		}
+	}
+	if c.encoder != nil {
+		c.encoder.Flush()
+	}
+	huma.Flush(c.humaContext.BodyWriter())
+}
+
+// SetWriteDeadline passes through to the underlying writer if supported.
+func (w *compressWriter) SetWriteDeadline(deadline time.Time) error {
+	return huma.SetWriteDeadline(w.humaContext.BodyWriter(), deadline)
+}
@@ -0,0 +1,149 @@
+package compress_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/compress"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/danielgtaylor/huma/v2/sse"
+)
+
+type Output struct {
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+func TestCompress(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(compress.New(compress.Options{MinSize: 100}))
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/message",
+	}, func(ctx context.Context, input *struct {
+		Size int `query:"size"`
+	}) (*Output, error) {
+		resp := &Output{}
+		resp.Body.Message = strings.Repeat("a", input.Size)
+		return resp, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/binary",
+	}, func(ctx context.Context, input *struct{}) (*struct {
+		ContentType string `header:"Content-Type"`
+		Body        []byte
+	}, error) {
+		return &struct {
+			ContentType string `header:"Content-Type"`
+			Body        []byte
+		}{ContentType: "image/png", Body: make([]byte, 1000)}, nil
+	})
+
+	// Large responses are compressed.
+	resp := api.Get("/message?size=1000", "Accept-Encoding: br;q=0.5, gzip")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header().Get("Vary"))
+	gz, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), strings.Repeat("a", 1000))
+
+	// Small responses are not compressed, but can vary.
+	resp = api.Get("/message?size=1", "Accept-Encoding: gzip")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header().Get("Vary"))
+	assert.Contains(t, resp.Body.String(), `"message":"a"`)
+
+	// Clients which don't accept an encoding get the plain response.
+	resp = api.Get("/message?size=1000", "Accept-Encoding: gzip;q=0, identity")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Contains(t, resp.Body.String(), strings.Repeat("a", 1000))
+
+	// Content types which are not in the allow list are not compressed.
+	resp = api.Get("/binary", "Accept-Encoding: gzip")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Empty(t, resp.Header().Get("Vary"))
+	assert.Len(t, resp.Body.Bytes(), 1000)
+}
+
+func TestAcceptEncoding(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(compress.New(compress.Options{MinSize: 100}))
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/message",
+	}, func(ctx context.Context, input *struct {
+		Size int `query:"size"`
+	}) (*Output, error) {
+		resp := &Output{}
+		resp.Body.Message = strings.Repeat("a", input.Size)
+		return resp, nil
+	})
+
+	for _, item := range []struct {
+		name     string
+		accept   string
+		size     int
+		encoding string
+	}{
+		{name: "none", accept: "", size: 1000, encoding: ""},
+		{name: "preferred", accept: "deflate;q=0.5, gzip", size: 1000, encoding: "gzip"},
+		{name: "tie", accept: "gzip, deflate", size: 1000, encoding: "deflate"},
+		{name: "unsupported", accept: "br", size: 1000, encoding: ""},
+		{name: "wildcard", accept: "*", size: 1000, encoding: "deflate"},
+		{name: "wildcard-excluded", accept: "*, deflate;q=0", size: 1000, encoding: "gzip"},
+		{name: "wildcard-lower", accept: "*;q=0.5, gzip", size: 1000, encoding: "gzip"},
+		{name: "wildcard-zero", accept: "*;q=0", size: 1000, encoding: ""},
+		{name: "identity-only", accept: "identity", size: 1000, encoding: ""},
+		{name: "small", accept: "gzip", size: 1, encoding: ""},
+		{name: "small-identity-refused", accept: "gzip, identity;q=0", size: 1, encoding: "gzip"},
+		{name: "small-wildcard-zero", accept: "gzip, *;q=0", size: 1, encoding: "gzip"},
+	} {
+		t.Run(item.name, func(t *testing.T) {
+			headers := []any{}
+			if item.accept != "" {
+				headers = append(headers, "Accept-Encoding: "+item.accept)
+			}
+			resp := api.Get("/message?size="+strconv.Itoa(item.size), headers...)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, item.encoding, resp.Header().Get("Content-Encoding"))
+		})
+	}
+}
+
+func TestCompressStream(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(compress.New(compress.Options{}))
+
+	sse.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/events",
+	}, map[string]any{
+		"message": Output{}.Body,
+	}, func(ctx context.Context, input *struct{}, send sse.Sender) {
+		send.Data(Output{}.Body)
+	})
+
+	// Streamed responses are compressed even if small, as each flush sends
+	// the data written so far.
+	resp := api.Get("/events", "Accept-Encoding: deflate, gzip")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "deflate", resp.Header().Get("Content-Encoding"))
+}
@@ -0,0 +1,100 @@
+package huma
+
+import (
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2/negotiation"
+)
+
+// bodyContent describes the request body media types declared by an
+// operation's `RequestBody.Content`, so that the right schema is used to
+// validate each request and undeclared content types can be rejected.
+type bodyContent struct {
+	// types are the declared media types in sorted order.
+	types []string
+
+	// schemas maps each declared media type to its schema.
+	schemas map[string]*Schema
+
+	// fallback is used when the client does not send a content type.
+	fallback string
+
+	// lenient accepts undeclared content types which the API has a format for,
+	// validating them against the fallback schema. This keeps e.g. CBOR working
+	// for operations which only document JSON.
+	lenient bool
+}
+
+// newBodyContent returns the accepted content for the request body. JSON is
+// preferred as the fallback when declared, otherwise the first declared type
+// is used.
+func newBodyContent(rb *RequestBody) *bodyContent {
+	b := &bodyContent{
+		schemas:  map[string]*Schema{},
+		fallback: "application/json",
+		lenient:  true,
+	}
+	if rb == nil {
+		return b
+	}
+	for ct, mt := range rb.Content {
+		var s *Schema
+		if mt != nil {
+			s = mt.Schema
+		}
+		b.types = append(b.types, ct)
+		b.schemas[ct] = s
+	}
+	sort.Strings(b.types)
+
+	if _, ok := rb.Content["application/json"]; !ok && len(b.types) > 0 {
+		b.fallback = b.types[0]
+		b.lenient = false
+	}
+	return b
+}
+
+// schema returns the schema used for the fallback content type.
+func (b *bodyContent) schema() *Schema {
+	return b.schemas[b.fallback]
+}
+
+// match returns the content type to unmarshal the body with and the schema to
+// validate it against for the given `Content-Type` request header. Returns
+// `false` if the content type is not accepted by the operation.
+func (b *bodyContent) match(header string) (string, *Schema, bool) {
+	if header == "" {
+		return b.fallback, b.schema(), true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return header, nil, false
+	}
+	if s, ok := b.schemas[mediaType]; ok {
+		return header, s, true
+	}
+	for _, ct := range b.types {
+		if strings.Contains(ct, "*") && matchContentType(mediaType, []string{ct}) {
+			return header, b.schemas[ct], true
+		}
+	}
+	if b.lenient {
+		return header, b.schema(), true
+	}
+	return header, nil, false
+}
+
+// unsupported returns the error message for a content type which is not
+// accepted by the operation.
+func (b *bodyContent) unsupported(header string) string {
+	return "unsupported content type " + header + ", expected one of " + strings.Join(b.types, ", ")
+}
+
+// acceptable returns whether the `Accept` request header can be satisfied by
+// any of the given content types. A missing header accepts anything.
+func acceptable(accept string, contentTypes []string) bool {
+	return accept == "" || negotiation.SelectQValueFast(accept, contentTypes) != ""
+}
@@ -0,0 +1,185 @@
+package huma
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures Cross-Origin Resource Sharing for an API, which lets
+// browsers call the API from web pages served by other origins. Since Huma
+// knows the methods registered for each path, it answers preflight `OPTIONS`
+// requests itself, so no router-specific CORS middleware is needed.
+//
+//	config := huma.DefaultConfig("My API", "1.0.0")
+//	config.CORS = &huma.CORS{
+//		AllowOrigins: []string{"https://example.com", "https://*.example.com"},
+//		AllowHeaders: []string{"Authorization", "Content-Type"},
+//		MaxAge:       time.Hour,
+//	}
+type CORS struct {
+	// AllowOrigins are the origins allowed to make requests, like
+	// `https://example.com`. A `*` matches any characters, so
+	// `https://*.example.com` allows all subdomains and `*` allows any origin.
+	// Allowing any origin cannot be combined with `AllowCredentials`.
+	AllowOrigins []string
+
+	// AllowHeaders are the request headers which clients may send, in
+	// addition to the CORS-safelisted headers. Use `*` to allow any header.
+	AllowHeaders []string
+
+	// ExposeHeaders are the response headers which client scripts may read, in
+	// addition to the CORS-safelisted headers, e.g. `ETag` or `Link`.
+	ExposeHeaders []string
+
+	// AllowCredentials allows requests with cookies or HTTP authentication.
+	// Responses then echo the request's origin rather than using `*`.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache preflight responses. If unset,
+	// browsers use their own default, which is usually a few seconds.
+	MaxAge time.Duration
+}
+
+// validate panics if the configuration is unsafe. Allowing credentialed
+// requests from any origin would let every website act on behalf of the
+// API's users.
+func (c *CORS) validate() {
+	if c.AllowCredentials && slicesContains(c.AllowOrigins, "*") {
+		panic("CORS cannot allow credentials from any origin, list the allowed origins instead")
+	}
+}
+
+// allowOrigin returns the value of the `Access-Control-Allow-Origin` header
+// for the request's origin, or an empty string if the origin is not allowed.
+func (c *CORS) allowOrigin(origin string) string {
+	for _, pattern := range c.AllowOrigins {
+		if pattern == "*" {
+			return "*"
+		}
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if strings.EqualFold(origin, pattern) {
+				return origin
+			}
+			continue
+		}
+		if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// setOrigin sets the headers shared by preflight and normal responses.
+// Returns false if the request's origin is not allowed.
+func (c *CORS) setOrigin(ctx Context, origin string) bool {
+	allowed := c.allowOrigin(origin)
+	if allowed != "*" {
+		// The response depends on the origin, so caches must store each one.
+		ctx.AppendHeader("Vary", "Origin")
+	}
+	if allowed == "" {
+		return false
+	}
+	ctx.SetHeader("Access-Control-Allow-Origin", allowed)
+	if c.AllowCredentials {
+		ctx.SetHeader("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// corsHandler wraps the handler to add CORS headers to responses for allowed
+// cross-origin requests.
+func corsHandler(cors *CORS, handle func(Context)) func(Context) {
+	exposed := strings.Join(cors.ExposeHeaders, ", ")
+	return func(ctx Context) {
+		if origin := ctx.Header("Origin"); origin != "" {
+			if cors.setOrigin(ctx, origin) && exposed != "" {
+				ctx.SetHeader("Access-Control-Expose-Headers", exposed)
+			}
+		}
+		handle(ctx)
+	}
+}
+
+// methodOrder is the order in which methods are listed in `Allow` headers.
+var methodOrder = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// sortMethods returns the methods in a consistent order.
+func sortMethods(methods []string) []string {
+	sorted := []string{}
+	for _, method := range methodOrder {
+		if slicesContains(methods, method) {
+			sorted = append(sorted, method)
+		}
+	}
+	return sorted
+}
+
+// pathMethods returns the documented methods for the path item in a
+// consistent order.
+func pathMethods(item *PathItem) []string {
+	methods := []string{}
+	if item == nil {
+		return methods
+	}
+	for i, op := range []*Operation{item.Get, item.Head, item.Post, item.Put, item.Patch, item.Delete, item.Options, item.Trace} {
+		if op != nil {
+			methods = append(methods, methodOrder[i])
+		}
+	}
+	return methods
+}
+
+// registerPreflight registers an `OPTIONS` handler for the path which answers
+// CORS preflight requests with the methods registered for the path at the
+// time of the request, including those of hidden operations. Plain `OPTIONS`
+// requests get an `Allow` header. API middleware is skipped as browsers send
+// preflight requests without credentials.
+func registerPreflight(api API, cors *CORS, path string) {
+	oapi := api.OpenAPI()
+	allowHeaders := strings.Join(cors.AllowHeaders, ", ")
+	anyHeader := slicesContains(cors.AllowHeaders, "*")
+	maxAge := ""
+	if cors.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(cors.MaxAge.Seconds()))
+	}
+
+	api.Adapter().Handle(&Operation{
+		Method: http.MethodOptions,
+		Path:   path,
+	}, matchRoute(func(ctx Context) {
+		allow := strings.Join(sortMethods(oapi.routes[path]), ", ")
+		ctx.SetHeader("Allow", allow)
+
+		origin := ctx.Header("Origin")
+		if origin != "" && ctx.Header("Access-Control-Request-Method") != "" && cors.setOrigin(ctx, origin) {
+			ctx.SetHeader("Access-Control-Allow-Methods", allow)
+			if anyHeader {
+				// Credentialed requests don't support `*`, so echo the headers
+				// which were requested instead.
+				if requested := ctx.Header("Access-Control-Request-Headers"); requested != "" {
+					ctx.SetHeader("Access-Control-Allow-Headers", requested)
+				}
+			} else if allowHeaders != "" {
+				ctx.SetHeader("Access-Control-Allow-Headers", allowHeaders)
+			}
+			if maxAge != "" {
+				ctx.SetHeader("Access-Control-Max-Age", maxAge)
+			}
+		}
+		ctx.SetStatus(http.StatusNoContent)
+	}))
+	oapi.addRoute(path, http.MethodOptions)
+}
@@ -0,0 +1,94 @@
+package huma
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownContentEncoding is returned when a request body uses a content
+// encoding which the API has no decoder for.
+var ErrUnknownContentEncoding = errors.New("unknown content encoding")
+
+// RequestDecoder decompresses a request body sent with a `Content-Encoding`
+// such as `gzip`. The returned reader is closed once the body has been read.
+type RequestDecoder func(r io.Reader) (io.ReadCloser, error)
+
+// DefaultRequestDecoders is a map of default request body decoders that can be
+// set in the API's `Config.RequestDecoders` map, keyed by content encoding, to
+// opt in to decompressing `gzip` and `deflate` request bodies. Other encodings
+// can be supported by adding decoders:
+//
+//	config := huma.DefaultConfig("My API", "1.0.0")
+//	config.RequestDecoders = map[string]huma.RequestDecoder{
+//		"gzip": huma.DefaultRequestDecoders["gzip"],
+//		"br": func(r io.Reader) (io.ReadCloser, error) {
+//			return io.NopCloser(brotli.NewReader(r)), nil
+//		},
+//	}
+var DefaultRequestDecoders = map[string]RequestDecoder{
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		// HTTP `deflate` is the zlib format rather than raw deflate.
+		return zlib.NewReader(r)
+	},
+}
+
+// requestEncodings returns the supported request content encodings in sorted
+// order.
+func requestEncodings(decoders map[string]RequestDecoder) []string {
+	encodings := make([]string, 0, len(decoders))
+	for enc := range decoders {
+		encodings = append(encodings, enc)
+	}
+	sort.Strings(encodings)
+	return encodings
+}
+
+// decodeRequestBody wraps the reader to undo each encoding listed in the
+// `Content-Encoding` header, which are listed in the order they were applied.
+// The returned closers must be closed once the body has been read. Returns an
+// error wrapping `ErrUnknownContentEncoding` for unsupported encodings. If no
+// decoders are configured then the body is passed through as is.
+func decodeRequestBody(decoders map[string]RequestDecoder, header string, r io.Reader) (io.Reader, []io.Closer, error) {
+	if header == "" || decoders == nil {
+		return r, nil, nil
+	}
+
+	encodings := strings.Split(header, ",")
+	closers := []io.Closer{}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		enc := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if enc == "" || enc == "identity" {
+			continue
+		}
+		decode, ok := decoders[enc]
+		if !ok {
+			return nil, closers, fmt.Errorf("%w %s, expected one of %s", ErrUnknownContentEncoding, enc, strings.Join(requestEncodings(decoders), ", "))
+		}
+		rc, err := decode(r)
+		if err != nil {
+			return nil, closers, fmt.Errorf("cannot decode %s request body: %w", enc, err)
+		}
+		closers = append(closers, rc)
+		r = rc
+	}
+	return r, closers, nil
+}
+
+// writeDecodeErr writes the error response for a request body which could not
+// be decoded, using 415 for unsupported encodings.
+func writeDecodeErr(api API, ctx Context, err error) {
+	if errors.Is(err, ErrUnknownContentEncoding) {
+		WriteErr(api, ctx, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+	WriteErr(api, ctx, http.StatusBadRequest, "cannot decode request body", err)
+}
@@ -0,0 +1,220 @@
+package huma
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isDeepObjectType returns whether a query parameter of the given type should
+// be serialized using the OpenAPI `deepObject` style, e.g. `?filter[a]=b`.
+// Special struct types which are parsed from a single string, including those
+// with a registered decoder, are excluded.
+func isDeepObjectType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String
+	case reflect.Struct:
+		return t != timeType && t != cookieType && paramDecoder(t) == nil
+	}
+	return false
+}
+
+// splitBracketKeys splits a key suffix like `[a][b][c]` into the keys `a`,
+// `b`, and `c`. A trailing `[]` is dropped to support repeated array keys
+// like `tags[]=a&tags[]=b`. Returns `false` if the suffix is malformed.
+func splitBracketKeys(rest string, keys []string) ([]string, bool) {
+	for len(rest) > 0 && rest[0] == '[' {
+		end := strings.IndexByte(rest, ']')
+		if end == -1 {
+			return nil, false
+		}
+		keys = append(keys, rest[1:end])
+		rest = rest[end+1:]
+	}
+	if rest != "" {
+		return nil, false
+	}
+	if len(keys) > 0 && keys[len(keys)-1] == "" {
+		keys = keys[:len(keys)-1]
+	}
+	return keys, len(keys) > 0
+}
+
+// setNested sets the values at the location given by the keys, creating
+// intermediate maps as needed. Repeated keys append to existing values.
+func setNested(result map[string]any, keys []string, values []string) {
+	current := result
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := current[k].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			current[k] = next
+		}
+		current = next
+	}
+	last := keys[len(keys)-1]
+	if existing, ok := current[last].([]string); ok {
+		values = append(existing, values...)
+	}
+	current[last] = values
+}
+
+// parseDeepObject collects all the query values for the given parameter name
+// into a nested map, e.g. `filter[status]=open&filter[owner][name]=bob`
+// becomes `{"status": ["open"], "owner": {"name": ["bob"]}}`. Leaf values are
+// always a slice of strings as keys may be repeated. Returns `nil` if no
+// values were sent for the parameter.
+func parseDeepObject(query url.Values, name string) map[string]any {
+	var result map[string]any
+	prefix := name + "["
+	for key, values := range query {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		keys, ok := splitBracketKeys(key[len(name):], nil)
+		if !ok {
+			// Malformed key, e.g. `filter[a` or `filter[a]b`, so ignore it.
+			continue
+		}
+
+		if result == nil {
+			result = map[string]any{}
+		}
+		setNested(result, keys, values)
+	}
+	return result
+}
+
+// coerceDeepObject converts the string values from `parseDeepObject` into the
+// types described by the schema so they can be validated and then decoded
+// into the parameter's Go type. Values which cannot be converted are reported
+// as errors at their location. A single array value is split on commas if
+// `split` is true.
+func coerceDeepObject(r Registry, s *Schema, pb *PathBuffer, v any, split bool, res *ValidateResult) any {
+	for s != nil && s.Ref != "" {
+		s = r.SchemaFromRef(s.Ref)
+	}
+	if s == nil {
+		return v
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for k, value := range v {
+			var prop *Schema
+			if s.Properties != nil {
+				prop = s.Properties[k]
+			}
+			if prop == nil {
+				prop, _ = s.AdditionalProperties.(*Schema)
+			}
+			pb.Push(k)
+			v[k] = coerceDeepObject(r, prop, pb, value, split, res)
+			pb.Pop()
+		}
+
+		// Fill in any defaults for properties which were not sent.
+		for k, prop := range s.Properties {
+			if _, ok := v[k]; !ok && prop.Default != nil {
+				v[k] = prop.Default
+			}
+		}
+		return v
+	case []string:
+		if s.Type == TypeArray {
+			if split && len(v) == 1 {
+				// A single value may contain comma-separated items.
+				v = strings.Split(v[0], ",")
+			}
+			items := make([]any, 0, len(v))
+			for i, item := range v {
+				pb.PushIndex(i)
+				items = append(items, coerceDeepObject(r, s.Items, pb, []string{item}, split, res))
+				pb.Pop()
+			}
+			return items
+		}
+
+		value := v[0]
+		switch s.Type {
+		case TypeBoolean:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				res.Add(pb, value, "invalid boolean")
+				return value
+			}
+			return b
+		case TypeInteger:
+			i, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				res.Add(pb, value, "invalid integer")
+				return value
+			}
+			// Use `float64` like `encoding/json` does so that e.g. enum values
+			// parsed from struct tags compare correctly during validation.
+			return float64(i)
+		case TypeNumber:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				res.Add(pb, value, "invalid float")
+				return value
+			}
+			return f
+		}
+		return value
+	}
+	return v
+}
+
+// decodeDeepObject converts, validates, and decodes the `deepObject` style
+// query parameter into the field. The path buffer should point to the
+// parameter, e.g. `query.filter`, so that errors are reported at locations
+// like `query.filter.status`. Returns whether the field was set.
+func decodeDeepObject(r Registry, p *paramFieldInfo, query url.Values, f reflect.Value, pb *PathBuffer, res *ValidateResult, validate bool) bool {
+	obj := parseDeepObject(query, p.Name)
+	if obj == nil {
+		if validate && p.Required {
+			res.Add(pb, "", "required "+p.Loc+" parameter is missing")
+		}
+		return false
+	}
+
+	return decodeNested(r, p.Schema, obj, true, f, pb, res, validate)
+}
+
+// decodeNested converts the nested string values to the types described by
+// the schema, optionally validates them, and then decodes them into the
+// field. Returns whether the field was set.
+func decodeNested(r Registry, s *Schema, obj map[string]any, split bool, f reflect.Value, pb *PathBuffer, res *ValidateResult, validate bool) bool {
+	count := len(res.Errors)
+	coerced := coerceDeepObject(r, s, pb, obj, split, res)
+	if len(res.Errors) > count {
+		return false
+	}
+
+	if validate {
+		Validate(r, s, pb, ModeWriteToServer, coerced, res)
+		if len(res.Errors) > count {
+			return false
+		}
+	}
+
+	// Round-trip through JSON to get the values into the right Go types using
+	// the same field names as the generated schema.
+	b, err := json.Marshal(coerced)
+	if err == nil {
+		err = json.Unmarshal(b, f.Addr().Interface())
+	}
+	if err != nil {
+		res.Add(pb, coerced, "invalid value: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package huma
+
+import (
+	"mime"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// formContentType is the media type of URL-encoded form bodies, as sent by
+// HTML forms and OAuth 2.0 token requests.
+const formContentType = "application/x-www-form-urlencoded"
+
+// isFormContentType returns whether the request content type is a URL-encoded
+// form, ignoring any parameters like `charset`.
+func isFormContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == formContentType
+}
+
+// parseForm collects the form values into a nested map using the same bracket
+// syntax as `deepObject` query params, e.g. `name=bob&owner[id]=1&tags=a&tags=b`
+// becomes `{"name": ["bob"], "owner": {"id": ["1"]}, "tags": ["a", "b"]}`.
+func parseForm(values url.Values) map[string]any {
+	result := map[string]any{}
+	for key, v := range values {
+		var keys []string
+		if i := strings.IndexByte(key, '['); i > 0 {
+			var ok bool
+			if keys, ok = splitBracketKeys(key[i:], []string{key[:i]}); !ok {
+				// Malformed key, e.g. `owner[id` or `owner[id]x`, so ignore it.
+				continue
+			}
+		} else if key != "" {
+			keys = []string{key}
+		} else {
+			continue
+		}
+		setNested(result, keys, v)
+	}
+	return result
+}
+
+// decodeForm converts, validates, and decodes the URL-encoded form values into
+// the body field. Unlike query params, array values are never split on commas
+// since forms send them as repeated keys. Returns whether the field was set.
+func decodeForm(r Registry, s *Schema, values url.Values, f reflect.Value, pb *PathBuffer, res *ValidateResult, validate bool) bool {
+	return decodeNested(r, s, parseForm(values), false, f, pb, res, validate)
+}
@@ -0,0 +1,161 @@
+package huma
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2/casing"
+)
+
+// registerHead registers and documents a `HEAD` operation which runs the
+// handler for the given `GET` operation, which must be wrapped by
+// `headHandler` to discard the response body. It is skipped if a `HEAD`
+// operation was already registered for the path.
+func registerHead(api API, get *Operation, handle func(Context)) {
+	oapi := api.OpenAPI()
+	if oapi.hasRoute(get.Path, http.MethodHead) {
+		return
+	}
+
+	// Name the operation based on the GET operation, e.g. `get-thing` becomes
+	// `head-thing`.
+	parts := casing.Split(get.OperationID)
+	if len(parts) > 1 && strings.ToLower(parts[0]) == "get" {
+		parts = parts[1:]
+	}
+	operationID := ""
+	if get.OperationID != "" {
+		operationID = "head-" + casing.Join(parts, "-")
+	}
+
+	// Responses have the same status codes and headers, but no body.
+	responses := make(map[string]*Response, len(get.Responses))
+	for status, resp := range get.Responses {
+		responses[status] = &Response{
+			Ref:         resp.Ref,
+			Description: resp.Description,
+			Headers:     resp.Headers,
+			Links:       resp.Links,
+			Extensions:  resp.Extensions,
+		}
+	}
+
+	op := *get
+	op.Method = http.MethodHead
+	op.OperationID = operationID
+	op.Description = "Same as the GET operation, but returns only the status and headers."
+	op.Responses = responses
+	op.Errors = nil
+
+	if !op.Hidden {
+		oapi.AddOperation(&op)
+	}
+
+	api.Adapter().Handle(&op, matchRoute(handle))
+	oapi.addRoute(op.Path, op.Method)
+}
+
+// headHandler wraps the handler to discard the response body of `HEAD`
+// requests.
+func headHandler(handle func(Context)) func(Context) {
+	return func(ctx Context) {
+		if ctx.Method() != http.MethodHead {
+			handle(ctx)
+			return
+		}
+		hc := &headContext{humaContext: ctx}
+		handle(hc)
+		hc.finish()
+	}
+}
+
+// headContext discards the response body while counting its size, so that
+// the `Content-Length` matches what the `GET` operation would have sent. The
+// status is held back until the handler is done so the header can be set.
+type headContext struct {
+	humaContext
+	status  int
+	length  int
+	hasLen  bool
+	flushed bool
+}
+
+func (c *headContext) SetStatus(code int) {
+	c.status = code
+	if c.flushed {
+		c.humaContext.SetStatus(code)
+	}
+}
+
+// Unwrap returns the wrapped context, see `huma.GetRemoteAddr`.
+func (c *headContext) Unwrap() Context {
+	return c.humaContext
+}
+
+func (c *headContext) Status() int {
+	if c.status != 0 {
+		return c.status
+	}
+	return c.humaContext.Status()
+}
+
+func (c *headContext) SetHeader(name, value string) {
+	if strings.EqualFold(name, "Content-Length") {
+		c.hasLen = true
+	}
+	c.humaContext.SetHeader(name, value)
+}
+
+func (c *headContext) AppendHeader(name, value string) {
+	if strings.EqualFold(name, "Content-Length") {
+		c.hasLen = true
+	}
+	c.humaContext.AppendHeader(name, value)
+}
+
+func (c *headContext) BodyWriter() io.Writer {
+	return (*headWriter)(c)
+}
+
+// finish sends the held back status along with the computed content length.
+func (c *headContext) finish() {
+	if c.flushed {
+		return
+	}
+	c.flushed = true
+	if !c.hasLen && c.length > 0 {
+		c.humaContext.SetHeader("Content-Length", strconv.Itoa(c.length))
+	}
+	if c.status != 0 {
+		c.humaContext.SetStatus(c.status)
+	}
+}
+
+// headWriter is the response body writer for a `headContext`.
+type headWriter headContext
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	w.length += len(p)
+	return len(p), nil
+}
+
+// Flush sends the status and headers for streaming responses, which have no
+// known length.
+func (w *headWriter) Flush() {
+	c := (*headContext)(w)
+	if !c.flushed {
+		c.flushed = true
+		if c.status != 0 {
+			c.humaContext.SetStatus(c.status)
+		}
+	}
+	Flush(c.humaContext.BodyWriter())
+}
+
+// SetWriteDeadline passes through to the underlying writer if supported.
+func (w *headWriter) SetWriteDeadline(deadline time.Time) error {
+	return SetWriteDeadline(w.humaContext.BodyWriter(), deadline)
+}
@@ -10,14 +10,15 @@ package huma
 import (
 	"bytes"
 	"context"
-	"encoding"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -68,6 +69,43 @@ func SetReadDeadline(w http.ResponseWriter, deadline time.Time) error {
 	}
 }
 
+// Flush is a utility to flush a response body writer, if possible, returning
+// whether it was flushed. Middleware which wraps `huma.Context.BodyWriter()`
+// can use it and `huma.SetWriteDeadline` to pass these calls through to the
+// writer it wraps, so streaming responses keep working.
+//
+//	func (w *myWriter) Flush() {
+//		huma.Flush(w.ctx.BodyWriter())
+//	}
+func Flush(w io.Writer) bool {
+	for {
+		switch t := w.(type) {
+		case http.Flusher:
+			t.Flush()
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// SetWriteDeadline is a utility to set the write deadline on a response body
+// writer, if possible, like `huma.SetReadDeadline`. See `huma.Flush`.
+func SetWriteDeadline(w io.Writer, deadline time.Time) error {
+	for {
+		switch t := w.(type) {
+		case interface{ SetWriteDeadline(time.Time) error }:
+			return t.SetWriteDeadline(deadline)
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return errDeadlineUnsupported
+		}
+	}
+}
+
 // StreamResponse is a response that streams data to the client. The body
 // function will be called once the response headers have been written and
 // the body writer is ready to be written to.
@@ -103,6 +141,26 @@ type paramFieldInfo struct {
 	Default    string
 	TimeFormat string
 	Schema     *Schema
+	IsPointer  bool
+	Style      string
+	Explode    bool
+	Delimiter  string
+
+	// Decode parses the value for types with a registered decoder or which
+	// implement `encoding.TextUnmarshaler`. If `DecodeItems` is set then it
+	// parses each item of a slice instead.
+	Decode      func(value string) (any, error)
+	DecodeItems bool
+}
+
+// paramStyles maps each parameter location to its supported serialization
+// styles and the delimiter used to separate array values for that style.
+// The first style listed for each location is the OpenAPI default.
+var paramStyles = map[string]map[string]string{
+	"path":   {"simple": ","},
+	"query":  {"form": ",", "spaceDelimited": " ", "pipeDelimited": "|"},
+	"header": {"simple": ","},
+	"cookie": {"form": ","},
 }
 
 func findParams(registry Registry, op *Operation, t reflect.Type) *findResult[*paramFieldInfo] {
@@ -111,18 +169,16 @@ func findParams(registry Registry, op *Operation, t reflect.Type) *findResult[*p
 			return nil
 		}
 
-		if f.Type.Kind() == reflect.Pointer {
-			// TODO: support pointers? The problem is that when we dynamically
-			// create an instance of the input struct the `params.Every(...)`
-			// call cannot set them as the value is `reflect.Invalid` unless
-			// dynamically allocated, but we don't know when to allocate until
-			// after the `Every` callback has run. Doable, but a bigger change.
-			panic("pointers are not supported for path/query/header parameters")
-		}
+		pfi := &paramFieldInfo{}
 
-		pfi := &paramFieldInfo{
-			Type: f.Type,
+		if f.Type.Kind() == reflect.Pointer {
+			// Pointer params are optional. They are left `nil` when the client
+			// does not send them and are allocated on demand otherwise. The docs
+			// and validation are generated from the element type.
+			pfi.IsPointer = true
+			f.Type = f.Type.Elem()
 		}
+		pfi.Type = f.Type
 
 		if def := f.Tag.Get("default"); def != "" {
 			pfi.Default = def
@@ -138,7 +194,8 @@ func findParams(registry Registry, op *Operation, t reflect.Type) *findResult[*p
 			pfi.Loc = "query"
 			name = q
 			// If `in` is `query` then `explode` defaults to true. Parsing is *much*
-			// easier if we use comma-separated values, so we disable explode.
+			// easier if we use comma-separated values, so we disable explode unless
+			// it is explicitly enabled via the `explode` tag.
 			nope := false
 			explode = &nope
 		} else if h := f.Tag.Get("header"); h != "" {
@@ -157,7 +214,70 @@ func findParams(registry Registry, op *Operation, t reflect.Type) *findResult[*p
 			return nil
 		}
 
-		pfi.Schema = SchemaFromField(registry, f, "")
+		pfi.Delimiter = ","
+		if style := f.Tag.Get("style"); style == "deepObject" || (style == "" && pfi.Loc == "query" && isDeepObjectType(f.Type)) {
+			// Objects are sent like `?filter[status]=open&filter[owner]=bob`,
+			// which OpenAPI requires to be documented as exploded.
+			if pfi.Loc != "query" || !isDeepObjectType(f.Type) {
+				panic(fmt.Sprintf("style 'deepObject' requires a struct or map for query parameter '%s'", name))
+			}
+			pfi.Style = "deepObject"
+			yes := true
+			explode = &yes
+		} else if style != "" {
+			delimiter, ok := paramStyles[pfi.Loc][style]
+			if !ok {
+				panic(fmt.Sprintf("unsupported style '%s' for %s parameter '%s'", style, pfi.Loc, name))
+			}
+			if delimiter != "," && f.Type.Kind() != reflect.Slice {
+				panic(fmt.Sprintf("style '%s' requires an array for %s parameter '%s'", style, pfi.Loc, name))
+			}
+			pfi.Style = style
+			pfi.Delimiter = delimiter
+		}
+
+		if _, ok := f.Tag.Lookup("explode"); ok {
+			pfi.Explode = boolTag(f, "explode")
+			if pfi.Explode && pfi.Loc != "query" {
+				panic(fmt.Sprintf("explode is only supported for query parameters, not %s parameter '%s'", pfi.Loc, name))
+			}
+			explode = &pfi.Explode
+		}
+
+		if pfi.Style != "deepObject" {
+			if decode := paramDecoder(f.Type); decode != nil {
+				pfi.Decode = decode
+			} else if f.Type.Kind() == reflect.Slice {
+				if decode := paramDecoder(f.Type.Elem()); decode != nil {
+					pfi.Decode = decode
+					pfi.DecodeItems = true
+				}
+			}
+		}
+
+		if pfi.Decode != nil {
+			// Special case: decoded structs and maps are sent as strings, so
+			// document them as such unless they provide their own schema.
+			t := f.Type
+			if pfi.DecodeItems {
+				t = t.Elem()
+			}
+			_, provider := reflect.New(t).Interface().(SchemaProvider)
+			if !provider && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map) {
+				if pfi.DecodeItems {
+					f.Type = reflect.SliceOf(stringType)
+				} else {
+					f.Type = stringType
+				}
+			}
+		}
+
+		hint := ""
+		if pfi.Style == "deepObject" {
+			// Anonymous structs need a name for the generated schema.
+			hint = op.OperationID + f.Name + "Param"
+		}
+		pfi.Schema = SchemaFromField(registry, f, hint)
 
 		var example any
 		if e := f.Tag.Get("example"); e != "" {
@@ -195,6 +315,7 @@ func findParams(registry Registry, op *Operation, t reflect.Type) *findResult[*p
 				Name:        name,
 				Description: desc,
 				In:          pfi.Loc,
+				Style:       pfi.Style,
 				Explode:     explode,
 				Required:    pfi.Required,
 				Schema:      pfi.Schema,
@@ -236,6 +357,16 @@ type headerInfo struct {
 }
 
 func findHeaders(t reflect.Type) *findResult[*headerInfo] {
+	ignore := []string{"Status", "Body"}
+	if t = deref(t); t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.Tag.Get("header") == "" && f.Tag.Get("status") != "" {
+				// This is a body for specific response statuses, so neither it nor
+				// its contents are headers.
+				ignore = append(ignore, f.Name)
+			}
+		}
+	}
 	return findInType(t, nil, func(sf reflect.StructField, i []int) *headerInfo {
 		header := sf.Tag.Get("header")
 		if header == "" {
@@ -249,7 +380,7 @@ func findHeaders(t reflect.Type) *findResult[*headerInfo] {
 			}
 		}
 		return &headerInfo{sf, header, timeFormat}
-	}, false, "Status", "Body")
+	}, false, ignore...)
 }
 
 type findResultPath[T comparable] struct {
@@ -275,6 +406,12 @@ func (r *findResult[T]) every(current reflect.Value, path []int, v T, f func(ref
 
 	switch current.Kind() {
 	case reflect.Struct:
+		if field := current.Field(path[0]); len(path) == 1 && field.Kind() == reflect.Pointer {
+			// Pass pointer fields through as-is so that the callback can allocate
+			// them on demand, for example for optional parameters.
+			f(field, v)
+			return
+		}
 		r.every(reflect.Indirect(current.Field(path[0])), path[1:], v, f)
 	case reflect.Slice:
 		for j := 0; j < current.Len(); j++ {
@@ -437,6 +574,24 @@ func _findInType[T comparable](t reflect.Type, path []int, result *findResult[T]
 	}
 }
 
+// statusesTag parses a comma-separated list of HTTP status codes from the
+// given struct field tag, returning `nil` if the tag is not set.
+func statusesTag(f reflect.StructField, tag string) []int {
+	v := f.Tag.Get(tag)
+	if v == "" {
+		return nil
+	}
+	statuses := []int{}
+	for _, part := range strings.Split(v, ",") {
+		status, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || status < 100 || status > 599 {
+			panic(fmt.Errorf("invalid status '%s' in tag '%s' for field '%s'", part, tag, f.Name))
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
 func getHint(parent reflect.Type, name string, other string) string {
 	if parent.Name() != "" {
 		return parent.Name() + name
@@ -467,10 +622,12 @@ var bufPool = sync.Pool{
 
 // transformAndWrite is a utility function to transform and write a response.
 // It is best-effort as the status code and headers may have already been sent.
-func transformAndWrite(api API, ctx Context, status int, ct string, body any) {
+func transformAndWrite(api API, ctx Context, tr *requestTrace, status int, ct string, body any) {
 	// Try to transform and then marshal/write the response.
 	// Status code was already sent, so just log the error if something fails,
 	// and do our best to stuff it into the body of the response.
+	defer tr.end()
+	tr.phase(ctx.Context(), "huma.transform")
 	tval, terr := api.Transform(ctx, strconv.Itoa(status), body)
 	if terr != nil {
 		ctx.BodyWriter().Write([]byte("error transforming response"))
@@ -478,6 +635,7 @@ func transformAndWrite(api API, ctx Context, status int, ct string, body any) {
 	}
 	ctx.SetStatus(status)
 	if status != http.StatusNoContent && status != http.StatusNotModified {
+		tr.phase(ctx.Context(), "huma.marshal")
 		if merr := api.Marshal(ctx.BodyWriter(), ct, tval); merr != nil {
 			ctx.BodyWriter().Write([]byte("error marshaling response"))
 			panic(fmt.Errorf("error marshaling response %+v for %s %s %d: %w\n", tval, ctx.Operation().Method, ctx.Operation().Path, status, merr))
@@ -578,20 +736,23 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				required = true
 			}
 
-			contentType := "application/json"
+			// Multiple accepted content types can be given as a comma-separated
+			// list, each of which is documented with the same schema.
+			contentTypes := []string{"application/json"}
 			if c := f.Tag.Get("contentType"); c != "" {
-				contentType = c
+				contentTypes = strings.Split(c, ",")
 			}
 
 			s := SchemaFromField(registry, f, getHint(inputType, f.Name, op.OperationID+"Request"))
 
 			op.RequestBody = &RequestBody{
 				Required: required,
-				Content: map[string]*MediaType{
-					contentType: {
-						Schema: s,
-					},
-				},
+				Content:  map[string]*MediaType{},
+			}
+			for _, contentType := range contentTypes {
+				op.RequestBody.Content[strings.TrimSpace(contentType)] = &MediaType{
+					Schema: s,
+				}
 			}
 		}
 
@@ -607,6 +768,8 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 	}
 	rawBodyIndex := -1
 	rawBodyMultipart := false
+	rawBodyStream := false
+	var rawBodyFormFields []*formFieldInfo
 	if f, ok := inputType.FieldByName("RawBody"); ok {
 		rawBodyIndex = f.Index[0]
 		if op.RequestBody == nil {
@@ -618,7 +781,14 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 
 		contentType := "application/octet-stream"
 
-		if f.Type.String() == "multipart.Form" {
+		if f.Type == readerType {
+			if inputBodyIndex != -1 {
+				panic("streaming RawBody io.Reader cannot be used with Body")
+			}
+			rawBodyStream = true
+		}
+
+		if f.Type.String() == "multipart.Form" || f.Type.Implements(reflect.TypeOf((*multipartFormType)(nil)).Elem()) {
 			contentType = "multipart/form-data"
 			rawBodyMultipart = true
 		}
@@ -627,8 +797,12 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 			contentType = c
 		}
 
-		switch contentType {
-		case "multipart/form-data":
+		switch {
+		case contentType == "multipart/form-data" && f.Type.String() != "multipart.Form":
+			var mt *MediaType
+			rawBodyFormFields, mt = findFormFields(registry, &op, f.Type)
+			op.RequestBody.Content["multipart/form-data"] = mt
+		case contentType == "multipart/form-data":
 			op.RequestBody.Content["multipart/form-data"] = &MediaType{
 				Schema: &Schema{
 					Type: "object",
@@ -655,9 +829,60 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 		}
 	}
 
-	var inSchema *Schema
-	if op.RequestBody != nil && op.RequestBody.Content != nil && op.RequestBody.Content["application/json"] != nil && op.RequestBody.Content["application/json"].Schema != nil {
-		inSchema = op.RequestBody.Content["application/json"].Schema
+	// Request bodies are validated against the schema documented for their
+	// content type, preferring JSON when the client doesn't send one.
+	inContent := newBodyContent(op.RequestBody)
+	inSchema := inContent.schema()
+	strictContent := configOf(api).StrictContentNegotiation
+
+	// Advertise the supported request body compression formats.
+	decoders := configOf(api).RequestDecoders
+	if op.SkipDecompressBody {
+		decoders = nil
+	}
+	if op.RequestBody != nil && len(decoders) > 0 && !rawBodyMultipart {
+		documented := false
+		for _, p := range op.Parameters {
+			if p.In == "header" && strings.EqualFold(p.Name, "Content-Encoding") {
+				documented = true
+			}
+		}
+		if !documented {
+			enum := []any{}
+			for _, enc := range requestEncodings(decoders) {
+				enum = append(enum, enc)
+			}
+			op.Parameters = append(op.Parameters, &Param{
+				Name:        "Content-Encoding",
+				Description: "Compression used for the request body, if any.",
+				In:          "header",
+				Schema:      &Schema{Type: TypeString, Enum: enum},
+			})
+		}
+	}
+	if strictContent {
+		// Only the declared content types are accepted.
+		inContent.lenient = false
+	}
+
+	// URL-encoded form bodies are only accepted when documented, as browsers
+	// can send them cross-origin without a CORS preflight request.
+	formBody := false
+	if inputBodyIndex != -1 && inContent.schemas[formContentType] != nil {
+		formBody = true
+	}
+
+	// Interface bodies with a discriminated `oneOf` schema are decoded into the
+	// concrete type selected by the discriminator property.
+	var inOneOf *Schema
+	if inputBodyIndex != -1 && inSchema != nil && inputType.Field(inputBodyIndex).Type.Kind() == reflect.Interface {
+		s := inSchema
+		for s != nil && s.Ref != "" {
+			s = registry.SchemaFromRef(s.Ref)
+		}
+		if s != nil && s.Discriminator != nil {
+			inOneOf = s
+		}
 	}
 
 	resolvers := findResolvers(resolverType, inputType)
@@ -672,86 +897,192 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 	}
 
 	outStatusIndex := -1
+	var outStatuses []int
 	if f, ok := outputType.FieldByName("Status"); ok {
 		outStatusIndex = f.Index[0]
 		if f.Type.Kind() != reflect.Int {
 			panic("status field must be an int")
 		}
-		// TODO: enum tag?
-		// TODO: register each of the possible responses with the right model
-		//       and headers down below.
+		// The possible statuses can be declared with the `enum` tag so that each
+		// one is documented with the right model and headers.
+		outStatuses = statusesTag(f, "enum")
 	}
 	outHeaders := findHeaders(outputType)
 	outBodyIndex := -1
 	outBodyFunc := false
+	var outBodyStream reflect.Type
+	var outBodyStatuses []int
 	if f, ok := outputType.FieldByName("Body"); ok {
 		outBodyIndex = f.Index[0]
-		if f.Type.Kind() == reflect.Func {
+		if f.Type.Implements(itemStreamerType) {
+			// Streamed collection of items, which documents the item schema.
+			outBodyStream = reflect.Zero(f.Type).Interface().(itemStreamer).itemType()
+		} else if f.Type.Kind() == reflect.Func {
 			outBodyFunc = true
 
 			if f.Type != bodyCallbackType {
 				panic("body field must be a function with signature func(huma.Context)")
 			}
 		}
-		status := op.DefaultStatus
-		if status == 0 {
-			status = http.StatusOK
+		outBodyStatuses = statusesTag(f, "status")
+	}
+
+	// Other fields with a `status` tag but no `header` tag are bodies used
+	// instead of `Body` for those statuses, e.g. a job description for a
+	// `202 Accepted` response.
+	outStatusBodies := map[int]int{}
+	for i := 0; i < outputType.NumField(); i++ {
+		f := outputType.Field(i)
+		if i == outBodyIndex || i == outStatusIndex || f.Tag.Get("header") != "" || f.Tag.Get("status") == "" {
+			continue
+		}
+		if f.Type.Kind() == reflect.Func {
+			panic("status body field must not be a function: " + f.Name)
+		}
+		for _, status := range statusesTag(f, "status") {
+			if _, ok := outStatusBodies[status]; ok {
+				panic(fmt.Sprintf("multiple body fields for status %d", status))
+			}
+			outStatusBodies[status] = i
 		}
+	}
+
+	// bodyFor returns the index of the body field to use for the given status,
+	// or -1 if the response has no body.
+	bodyFor := func(status int) int {
+		if i, ok := outStatusBodies[status]; ok {
+			return i
+		}
+		if outBodyStatuses == nil || slicesContains(outBodyStatuses, status) {
+			return outBodyIndex
+		}
+		return -1
+	}
+
+	if op.DefaultStatus == 0 {
+		if len(outStatuses) > 0 {
+			op.DefaultStatus = outStatuses[0]
+		} else if outBodyIndex != -1 {
+			op.DefaultStatus = http.StatusOK
+		} else {
+			op.DefaultStatus = http.StatusNoContent
+		}
+	}
+
+	statuses := []int{op.DefaultStatus}
+	for _, status := range outStatuses {
+		if !slicesContains(statuses, status) {
+			statuses = append(statuses, status)
+		}
+	}
+	for status := range outStatusBodies {
+		if !slicesContains(statuses, status) {
+			panic(fmt.Sprintf("body field for undeclared status %d", status))
+		}
+	}
+
+	for _, status := range statuses {
 		statusStr := strconv.Itoa(status)
 		if op.Responses[statusStr] == nil {
 			op.Responses[statusStr] = &Response{}
 		}
-		if op.Responses[statusStr].Description == "" {
-			op.Responses[statusStr].Description = http.StatusText(status)
+		resp := op.Responses[statusStr]
+		if resp.Description == "" {
+			resp.Description = http.StatusText(status)
 		}
-		if op.Responses[statusStr].Headers == nil {
-			op.Responses[statusStr].Headers = map[string]*Param{}
+
+		if i := bodyFor(status); i != -1 {
+			if resp.Headers == nil {
+				resp.Headers = map[string]*Param{}
+			}
+			if i == outBodyIndex && outBodyStream != nil {
+				itemSchema := registry.Schema(outBodyStream, true, getHint(outputType, "Body", op.OperationID+"Item"))
+				if resp.Content == nil {
+					resp.Content = itemStreamContent(itemSchema)
+				}
+			} else if i != outBodyIndex || !outBodyFunc {
+				f := outputType.Field(i)
+				hint := op.OperationID + "Response"
+				if i != outBodyIndex {
+					hint = op.OperationID + statusStr + "Response"
+				}
+				outSchema := SchemaFromField(registry, f, getHint(outputType, f.Name, hint))
+				if resp.Content == nil {
+					resp.Content = map[string]*MediaType{}
+				}
+				if len(resp.Content) == 0 {
+					resp.Content["application/json"] = &MediaType{}
+				}
+				if resp.Content["application/json"] != nil && resp.Content["application/json"].Schema == nil {
+					resp.Content["application/json"].Schema = outSchema
+				}
+			}
 		}
-		if !outBodyFunc {
-			outSchema := SchemaFromField(registry, f, getHint(outputType, f.Name, op.OperationID+"Response"))
-			if op.Responses[statusStr].Content == nil {
-				op.Responses[statusStr].Content = map[string]*MediaType{}
+
+		for _, entry := range outHeaders.Paths {
+			v := entry.Value
+			f := v.Field
+			if headerStatuses := statusesTag(f, "status"); headerStatuses != nil && !slicesContains(headerStatuses, status) {
+				continue
 			}
-			if len(op.Responses[statusStr].Content) == 0 {
-				op.Responses[statusStr].Content["application/json"] = &MediaType{}
+
+			// Document the header's name and type.
+			if resp.Headers == nil {
+				resp.Headers = map[string]*Param{}
 			}
-			if op.Responses[statusStr].Content["application/json"] != nil && op.Responses[statusStr].Content["application/json"].Schema == nil {
-				op.Responses[statusStr].Content["application/json"].Schema = outSchema
+			if f.Type.Kind() == reflect.Slice {
+				f.Type = deref(f.Type.Elem())
+			}
+			if reflect.PointerTo(f.Type).Implements(fmtStringerType) {
+				// Special case: this field will be written as a string by calling
+				// `.String()` on the value.
+				f.Type = stringType
+			}
+			resp.Headers[v.Name] = &Header{
+				// We need to generate the schema from the field to get validation info
+				// like min/max and enums. Useful to let the client know possible values.
+				Schema: SchemaFromField(registry, f, getHint(outputType, f.Name, op.OperationID+statusStr+v.Name)),
 			}
 		}
 	}
-	if op.DefaultStatus == 0 {
-		if outBodyIndex != -1 {
-			op.DefaultStatus = http.StatusOK
-		} else {
-			op.DefaultStatus = http.StatusNoContent
+
+	// In strict mode the `Accept` header must match one of the formats the
+	// response body can be written in. Bodies written as raw bytes or by a
+	// callback set their own content type, so they are not checked.
+	var outContentTypes []string
+	if strictContent {
+		if outBodyStream != nil {
+			outContentTypes = itemStreamContentTypes
+		} else if (outBodyIndex != -1 && !outBodyFunc && outputType.Field(outBodyIndex).Type != reflect.TypeOf([]byte{})) || len(outStatusBodies) > 0 {
+			for ct := range configOf(api).Formats {
+				if strings.Contains(ct, "/") {
+					// Skip suffix formats like `json` for `+json` types.
+					outContentTypes = append(outContentTypes, ct)
+				}
+			}
+			sort.Strings(outContentTypes)
 		}
-	}
-	defaultStatusStr := strconv.Itoa(op.DefaultStatus)
-	if op.Responses[defaultStatusStr] == nil {
-		op.Responses[defaultStatusStr] = &Response{
-			Description: http.StatusText(op.DefaultStatus),
+		for _, entry := range outHeaders.Paths {
+			if strings.EqualFold(entry.Value.Name, "Content-Type") {
+				outContentTypes = nil
+			}
 		}
 	}
-	for _, entry := range outHeaders.Paths {
-		// Document the header's name and type.
-		if op.Responses[defaultStatusStr].Headers == nil {
-			op.Responses[defaultStatusStr].Headers = map[string]*Param{}
-		}
-		v := entry.Value
-		f := v.Field
-		if f.Type.Kind() == reflect.Slice {
-			f.Type = deref(f.Type.Elem())
-		}
-		if reflect.PointerTo(f.Type).Implements(fmtStringerType) {
-			// Special case: this field will be written as a string by calling
-			// `.String()` on the value.
-			f.Type = stringType
-		}
-		op.Responses[defaultStatusStr].Headers[v.Name] = &Header{
-			// We need to generate the schema from the field to get validation info
-			// like min/max and enums. Useful to let the client know possible values.
-			Schema: SchemaFromField(registry, f, getHint(outputType, f.Name, op.OperationID+defaultStatusStr+v.Name)),
+
+	security := newSecurity(api, &op)
+	if security.required() && !slicesContains(op.Errors, http.StatusUnauthorized) {
+		op.Errors = append(op.Errors, http.StatusUnauthorized)
+	}
+	if security.scoped() && !slicesContains(op.Errors, http.StatusForbidden) {
+		op.Errors = append(op.Errors, http.StatusForbidden)
+	}
+
+	if strictContent {
+		if op.RequestBody != nil && !slicesContains(op.Errors, http.StatusUnsupportedMediaType) {
+			op.Errors = append(op.Errors, http.StatusUnsupportedMediaType)
+		}
+		if outContentTypes != nil && !slicesContains(op.Errors, http.StatusNotAcceptable) {
+			op.Errors = append(op.Errors, http.StatusNotAcceptable)
 		}
 	}
 
@@ -791,13 +1122,41 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 		}
 	}
 
+	// CORS preflight requests are answered for each path, so only the first
+	// operation on a path registers the handler, unless the path has its own
+	// `OPTIONS` operation.
+	cors := configOf(api).CORS
+	preflight := false
+	if cors != nil {
+		cors.validate()
+		hasOptions := oapi.hasRoute(op.Path, http.MethodOptions)
+		if op.Method == http.MethodOptions && hasOptions {
+			panic("OPTIONS operation for " + op.Path + " conflicts with the CORS preflight handler, register it before other operations on the path")
+		}
+		preflight = op.Method != http.MethodOptions && !hasOptions
+	}
+
 	if !op.Hidden {
 		oapi.AddOperation(&op)
 	}
 
 	a := api.Adapter()
 
-	a.Handle(&op, api.Middlewares().Handler(op.Middlewares.Handler(func(ctx Context) {
+	responses := &responseValidator{registry: registry, op: &op, mode: op.ResponseValidation}
+	if responses.mode == ResponseValidationDefault {
+		responses.mode = configOf(api).ResponseValidation
+	}
+	validateResponses := responses.mode == ResponseValidationReport || responses.mode == ResponseValidationError
+
+	tracing := configOf(api).Tracer != nil
+	metered := configOf(api).Metrics != nil
+
+	handle := api.Middlewares().Handler(security.handler(op.Middlewares.Handler(func(ctx Context) {
+		if outContentTypes != nil && !acceptable(ctx.Header("Accept"), outContentTypes) {
+			WriteErr(api, ctx, http.StatusNotAcceptable, "unable to satisfy accept header, expected one of "+strings.Join(outContentTypes, ", "))
+			return
+		}
+
 		var input I
 
 		// Get the validation dependencies from the shared pool.
@@ -813,15 +1172,54 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 		errStatus := http.StatusUnprocessableEntity
 
 		var cookies map[string]*http.Cookie
+		var query url.Values
+
+		var tr *requestTrace
+		if tracing {
+			tr = traceFrom(ctx)
+		}
+		tr.phase(ctx.Context(), "huma.params")
 
 		v := reflect.ValueOf(&input).Elem()
 		inputParams.Every(v, func(f reflect.Value, p *paramFieldInfo) {
+			// Keep track of pointer fields so they can be allocated only if a value
+			// was actually sent by the client.
+			var ptr reflect.Value
+			if p.IsPointer {
+				ptr = f
+				f = reflect.New(p.Type).Elem()
+			}
+
 			var value string
+			var exploded []string
 			switch p.Loc {
 			case "path":
 				value = ctx.Param(p.Name)
 			case "query":
-				value = ctx.Query(p.Name)
+				if p.Style == "deepObject" || (p.Explode && p.Type.Kind() == reflect.Slice) {
+					if query == nil {
+						// Only parse the full query once, on-demand, since exploded values
+						// are sent as repeated keys like `?tag=a&tag=b` and deep objects
+						// are spread across keys like `?filter[a]=b`.
+						u := ctx.URL()
+						query = u.Query()
+					}
+				}
+				if p.Style == "deepObject" {
+					pb.Reset()
+					pb.Push(p.Loc)
+					pb.Push(p.Name)
+					if decodeDeepObject(oapi.Components.Schemas, p, query, f, pb, res, !op.SkipValidateParams) && p.IsPointer {
+						ptr.Set(f.Addr())
+					}
+					return
+				}
+				if p.Explode && p.Type.Kind() == reflect.Slice {
+					exploded = query[p.Name]
+					value = strings.Join(exploded, ",")
+				} else {
+					value = ctx.Query(p.Name)
+				}
 			case "header":
 				value = ctx.Header(p.Name)
 			case "cookie":
@@ -836,6 +1234,10 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 					// Special case: http.Cookie type, meaning we want the entire parsed
 					// cookie struct, not just the value.
 					if f.Type() == cookieType {
+						if p.IsPointer {
+							ptr.Set(reflect.ValueOf(cookies[p.Name]))
+							return
+						}
 						f.Set(reflect.ValueOf(cookies[p.Name]).Elem())
 						return
 					}
@@ -848,8 +1250,12 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 			pb.Push(p.Loc)
 			pb.Push(p.Name)
 
+			delimiter := p.Delimiter
 			if value == "" && p.Default != "" {
+				// Defaults are always comma-separated, regardless of the style.
 				value = p.Default
+				delimiter = ","
+				exploded = nil
 			}
 
 			if !op.SkipValidateParams && p.Required && value == "" {
@@ -858,6 +1264,20 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				return
 			}
 
+			if value != "" && p.Decode != nil {
+				pv, ok := decodeParam(p, f, value, exploded, delimiter, pb, res)
+				if !ok {
+					return
+				}
+				if p.IsPointer {
+					ptr.Set(f.Addr())
+				}
+				if !op.SkipValidateParams {
+					Validate(oapi.Components.Schemas, p.Schema, pb, ModeWriteToServer, pv, res)
+				}
+				return
+			}
+
 			if value != "" {
 				var pv any
 
@@ -899,15 +1319,18 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 					pv = v
 				default:
 					if f.Type().Kind() == reflect.Slice {
+						values := exploded
+						if values == nil {
+							values = strings.Split(value, delimiter)
+						}
+
 						switch f.Type().Elem().Kind() {
 
 						case reflect.String:
-							values := strings.Split(value, ",")
 							f.Set(reflect.ValueOf(values))
 							pv = values
 
 						case reflect.Int:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (int, error) {
 								val, err := strconv.ParseInt(s, 10, strconv.IntSize)
 								if err != nil {
@@ -923,7 +1346,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Int8:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (int8, error) {
 								val, err := strconv.ParseInt(s, 10, 8)
 								if err != nil {
@@ -939,7 +1361,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Int16:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (int16, error) {
 								val, err := strconv.ParseInt(s, 10, 16)
 								if err != nil {
@@ -955,7 +1376,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Int32:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (int32, error) {
 								val, err := strconv.ParseInt(s, 10, 32)
 								if err != nil {
@@ -971,7 +1391,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Int64:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (int64, error) {
 								val, err := strconv.ParseInt(s, 10, 64)
 								if err != nil {
@@ -987,7 +1406,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Uint:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (uint, error) {
 								val, err := strconv.ParseUint(s, 10, strconv.IntSize)
 								if err != nil {
@@ -1003,7 +1421,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Uint16:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (uint16, error) {
 								val, err := strconv.ParseUint(s, 10, 16)
 								if err != nil {
@@ -1019,7 +1436,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Uint32:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (uint32, error) {
 								val, err := strconv.ParseUint(s, 10, 32)
 								if err != nil {
@@ -1035,7 +1451,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Uint64:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (uint64, error) {
 								val, err := strconv.ParseUint(s, 10, 64)
 								if err != nil {
@@ -1051,7 +1466,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Float32:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (float32, error) {
 								val, err := strconv.ParseFloat(s, 32)
 								if err != nil {
@@ -1067,7 +1481,6 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pv = vs
 
 						case reflect.Float64:
-							values := strings.Split(value, ",")
 							vs, err := parseArrElement(values, func(s string) (float64, error) {
 								val, err := strconv.ParseFloat(s, 64)
 								if err != nil {
@@ -1097,19 +1510,13 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 						break
 					}
 
-					// Last resort: use the `encoding.TextUnmarshaler` interface.
-					if fn, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
-						if err := fn.UnmarshalText([]byte(value)); err != nil {
-							res.Add(pb, value, "invalid value: "+err.Error())
-							return
-						}
-						pv = value
-						break
-					}
-
 					panic("unsupported param type " + p.Type.String())
 				}
 
+				if p.IsPointer {
+					ptr.Set(f.Addr())
+				}
+
 				if !op.SkipValidateParams {
 					Validate(oapi.Components.Schemas, p.Schema, pb, ModeWriteToServer, pv, res)
 				}
@@ -1118,6 +1525,14 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 
 		// Read input body if defined.
 		if inputBodyIndex != -1 || rawBodyIndex != -1 {
+			tr.phase(ctx.Context(), "huma.body.read")
+			if strictContent {
+				if _, _, ok := inContent.match(ctx.Header("Content-Type")); !ok {
+					WriteErr(api, ctx, http.StatusUnsupportedMediaType, inContent.unsupported(ctx.Header("Content-Type")))
+					return
+				}
+			}
+
 			if op.BodyReadTimeout > 0 {
 				ctx.SetReadDeadline(time.Now().Add(op.BodyReadTimeout))
 			} else if op.BodyReadTimeout < 0 {
@@ -1125,13 +1540,48 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				ctx.SetReadDeadline(time.Time{})
 			}
 
-			if rawBodyMultipart {
+			if rawBodyStream {
+				// Stream the body to the handler without buffering it. Any read
+				// timeout is extended on each read while streaming.
+				reader := ctx.BodyReader()
+				if reader == nil {
+					reader = bytes.NewReader(nil)
+				}
+				if closer, ok := reader.(io.Closer); ok {
+					defer closer.Close()
+				}
+				reader, closers, err := decodeRequestBody(decoders, ctx.Header("Content-Encoding"), reader)
+				for _, closer := range closers {
+					defer closer.Close()
+				}
+				if err != nil {
+					writeDecodeErr(api, ctx, err)
+					return
+				}
+				v.Field(rawBodyIndex).Set(reflect.ValueOf(&streamBodyReader{
+					ctx:      ctx,
+					reader:   reader,
+					timeout:  op.BodyReadTimeout,
+					maxBytes: op.MaxBodyBytes,
+				}))
+			} else if rawBodyMultipart {
 				form, err := ctx.GetMultipartForm()
 				if err != nil || form == nil {
 					res.Errors = append(res.Errors, &ErrorDetail{
 						Location: "body",
 						Message:  "cannot read multipart form: " + err.Error(),
 					})
+				} else if rawBodyFormFields != nil {
+					f := v.Field(rawBodyIndex)
+					f.FieldByName("Form").Set(reflect.ValueOf(form))
+					pb.Reset()
+					pb.Push("body")
+					files := decodeMultipartForm(oapi.Components.Schemas, rawBodyFormFields, form, f.FieldByName("Data"), pb, res, !op.SkipValidateBody)
+					defer func() {
+						for _, file := range files {
+							file.Close()
+						}
+					}()
 				} else {
 					f := v.Field(rawBodyIndex)
 					f.Set(reflect.ValueOf(*form))
@@ -1145,7 +1595,18 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				if closer, ok := reader.(io.Closer); ok {
 					defer closer.Close()
 				}
+				reader, closers, err := decodeRequestBody(decoders, ctx.Header("Content-Encoding"), reader)
+				for _, closer := range closers {
+					defer closer.Close()
+				}
+				if err != nil {
+					buf.Reset()
+					bufPool.Put(buf)
+					writeDecodeErr(api, ctx, err)
+					return
+				}
 				if op.MaxBodyBytes > 0 {
+					// Limit the decompressed size, not just what was sent.
 					reader = io.LimitReader(reader, op.MaxBodyBytes)
 				}
 				count, err := io.Copy(buf, reader)
@@ -1166,16 +1627,29 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 						return
 					}
 
+					if len(closers) > 0 {
+						WriteErr(api, ctx, http.StatusBadRequest, "cannot decode request body", err)
+						return
+					}
+
 					WriteErr(api, ctx, http.StatusInternalServerError, "cannot read request body", err)
 					return
 				}
 				body := buf.Bytes()
+				tr.phase(ctx.Context(), "huma.body.validate")
 
 				if rawBodyIndex != -1 {
 					f := v.Field(rawBodyIndex)
 					f.SetBytes(body)
 				}
 
+				contentType := ctx.Header("Content-Type")
+				bodySchema := inSchema
+				contentOK := true
+				if inputBodyIndex != -1 {
+					contentType, bodySchema, contentOK = inContent.match(contentType)
+				}
+
 				if len(body) == 0 {
 					if op.RequestBody != nil && op.RequestBody.Required {
 						buf.Reset()
@@ -1183,14 +1657,44 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 						WriteErr(api, ctx, http.StatusBadRequest, "request body is required", res.Errors...)
 						return
 					}
+				} else if formBody && isFormContentType(ctx.Header("Content-Type")) {
+					pb.Reset()
+					pb.Push("body")
+					if values, err := url.ParseQuery(string(body)); err != nil {
+						errStatus = http.StatusBadRequest
+						res.Errors = append(res.Errors, &ErrorDetail{
+							Location: "body",
+							Message:  "invalid form body: " + err.Error(),
+							Value:    string(body),
+						})
+					} else if decodeForm(oapi.Components.Schemas, inSchema, values, v.Field(inputBodyIndex), pb, res, !op.SkipValidateBody) {
+						// Set defaults for any fields that were not in the input.
+						defaults.Every(v, func(item reflect.Value, def any) {
+							if item.IsZero() {
+								item.Set(reflect.Indirect(reflect.ValueOf(def)))
+							}
+						})
+					}
+
+					buf.Reset()
+					bufPool.Put(buf)
+				} else if !contentOK {
+					errStatus = http.StatusUnsupportedMediaType
+					res.Errors = append(res.Errors, &ErrorDetail{
+						Location: "body",
+						Message:  inContent.unsupported(contentType),
+					})
+
+					buf.Reset()
+					bufPool.Put(buf)
 				} else {
 					parseErrCount := 0
-					if inputBodyIndex != -1 && !op.SkipValidateBody {
+					var parsed any
+					if inputBodyIndex != -1 && !op.SkipValidateBody && bodySchema != nil {
 						// Validate the input. First, parse the body into []any or map[string]any
 						// or equivalent, which can be easily validated. Then, convert to the
 						// expected struct type to call the handler.
-						var parsed any
-						if err := api.Unmarshal(ctx.Header("Content-Type"), body, &parsed); err != nil {
+						if err := api.Unmarshal(contentType, body, &parsed); err != nil {
 							errStatus = http.StatusBadRequest
 							if errors.Is(err, ErrUnknownContentType) {
 								errStatus = http.StatusUnsupportedMediaType
@@ -1205,7 +1709,7 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 							pb.Reset()
 							pb.Push("body")
 							count := len(res.Errors)
-							Validate(oapi.Components.Schemas, inSchema, pb, ModeWriteToServer, parsed, res)
+							Validate(oapi.Components.Schemas, bodySchema, pb, ModeWriteToServer, parsed, res)
 							parseErrCount = len(res.Errors) - count
 							if parseErrCount > 0 {
 								errStatus = http.StatusUnprocessableEntity
@@ -1220,7 +1724,29 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 						// common reflection-based approaches when using real-world medium-sized
 						// JSON payloads with lots of strings.
 						f := v.Field(inputBodyIndex)
-						if err := api.Unmarshal(ctx.Header("Content-Type"), body, f.Addr().Interface()); err != nil {
+						target := f.Addr().Interface()
+						var concrete reflect.Value
+						var err error
+						if inOneOf != nil {
+							if parsed == nil {
+								err = api.Unmarshal(contentType, body, &parsed)
+							}
+							var t reflect.Type
+							if err == nil {
+								t, err = oneOfType(oapi.Components.Schemas, inOneOf, parsed)
+							}
+							if err == nil {
+								concrete = reflect.New(deref(t))
+								target = concrete.Interface()
+								if t.Kind() != reflect.Pointer {
+									concrete = concrete.Elem()
+								}
+							}
+						}
+						if err == nil {
+							err = api.Unmarshal(contentType, body, target)
+						}
+						if err != nil {
 							if parseErrCount == 0 {
 								// Hmm, this should have worked... validator missed something?
 								res.Errors = append(res.Errors, &ErrorDetail{
@@ -1230,6 +1756,9 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 								})
 							}
 						} else {
+							if concrete.IsValid() {
+								f.Set(concrete)
+							}
 							// Set defaults for any fields that were not in the input.
 							defaults.Every(v, func(item reflect.Value, def any) {
 								if item.IsZero() {
@@ -1245,6 +1774,9 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 			}
 		}
 
+		if len(resolvers.Paths) > 0 {
+			tr.phase(ctx.Context(), "huma.resolve")
+		}
 		resolvers.EveryPB(pb, v, func(item reflect.Value, _ bool) {
 			if resolver, ok := item.Addr().Interface().(Resolver); ok {
 				if errs := resolver.Resolve(ctx); len(errs) > 0 {
@@ -1269,11 +1801,22 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 					break
 				}
 			}
+			tr.invalid(res.Errors)
+			tr.end()
 			WriteErr(api, ctx, errStatus, "validation failed", res.Errors...)
 			return
 		}
 
-		output, err := handler(ctx.Context(), &input)
+		if metered {
+			if rm := metricsFrom(ctx); rm != nil {
+				rm.handlerCalled = true
+			}
+		}
+		output, err := handler(tr.phase(ctx.Context(), "huma.handler"), &input)
+		if err != nil {
+			tr.fail(err)
+		}
+		tr.end()
 		if err != nil {
 			status := http.StatusInternalServerError
 			var se StatusError
@@ -1289,42 +1832,104 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 			}
 
 			ctx.SetHeader("Content-Type", ct)
-			transformAndWrite(api, ctx, status, ct, err)
+			transformAndWrite(api, ctx, tr, status, ct, err)
 			return
 		}
 
-		// Serialize output headers
-		ct := ""
+		status := op.DefaultStatus
 		vo := reflect.ValueOf(output).Elem()
+		bodyIndex := outBodyIndex
+		if outStatusIndex != -1 {
+			if s := int(vo.Field(outStatusIndex).Int()); s != 0 {
+				status = s
+			}
+			bodyIndex = bodyFor(status)
+		}
+
+		if op.StrictStatus && !slicesContains(statuses, status) {
+			WriteErr(api, ctx, http.StatusInternalServerError, fmt.Sprintf("undeclared response status %d", status))
+			return
+		}
+
+		// Serialize output headers. When validating responses they are held
+		// back until the response is known to be valid, so that an error
+		// response does not carry the headers of the failed response.
+		setHeader, appendHeader := ctx.SetHeader, ctx.AppendHeader
+		var pending []func()
+		if validateResponses {
+			setHeader = func(name, value string) {
+				pending = append(pending, func() { ctx.SetHeader(name, value) })
+			}
+			appendHeader = func(name, value string) {
+				pending = append(pending, func() { ctx.AppendHeader(name, value) })
+			}
+		}
+		valid := func() bool {
+			if !validateResponses {
+				return true
+			}
+			if !responses.check(api, ctx, status, res) {
+				return false
+			}
+			for _, write := range pending {
+				write()
+			}
+			return true
+		}
+
+		ct := ""
 		outHeaders.Every(vo, func(f reflect.Value, info *headerInfo) {
+			if f.Kind() == reflect.Pointer {
+				if f.IsNil() {
+					return
+				}
+				f = f.Elem()
+			}
 			if f.Kind() == reflect.Slice {
 				for i := 0; i < f.Len(); i++ {
-					writeHeader(ctx.AppendHeader, info, f.Index(i))
+					if validateResponses {
+						responses.validateHeader(pb, res, status, info, f.Index(i))
+					}
+					writeHeader(appendHeader, info, f.Index(i))
 				}
 			} else {
+				if validateResponses {
+					responses.validateHeader(pb, res, status, info, f)
+				}
 				if f.Kind() == reflect.String && info.Name == "Content-Type" {
 					// Track custom content type.
 					ct = f.String()
 				}
-				writeHeader(ctx.SetHeader, info, f)
+				writeHeader(setHeader, info, f)
 			}
 		})
 
-		status := op.DefaultStatus
-		if outStatusIndex != -1 {
-			status = int(vo.Field(outStatusIndex).Int())
-		}
-
-		if outBodyIndex != -1 {
+		if bodyIndex != -1 {
 			// Serialize output body
-			body := vo.Field(outBodyIndex).Interface()
+			body := vo.Field(bodyIndex).Interface()
 
-			if outBodyFunc {
+			if outBodyFunc && bodyIndex == outBodyIndex {
+				if !valid() {
+					return
+				}
 				body.(func(Context))(ctx)
 				return
 			}
 
+			if outBodyStream != nil && bodyIndex == outBodyIndex {
+				// Items are produced lazily while writing, so only the headers
+				// can be validated before the stream starts.
+				if !valid() {
+					return
+				}
+				writeItemStream(api, ctx, status, body.(itemStreamer))
+				return
+			}
+
 			if b, ok := body.([]byte); ok {
+				if !valid() {
+					return
+				}
 				ctx.SetStatus(status)
 				ctx.BodyWriter().Write(b)
 				return
@@ -1342,14 +1947,55 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 					ct = ctf.ContentType(ct)
 				}
 
-				ctx.SetHeader("Content-Type", ct)
+				setHeader("Content-Type", ct)
+			}
+
+			if validateResponses {
+				responses.validateBody(pb, res, status, ct, body)
+			}
+			if !valid() {
+				return
 			}
 
-			transformAndWrite(api, ctx, status, ct, body)
+			transformAndWrite(api, ctx, tr, status, ct, body)
 		} else {
+			if validateResponses {
+				responses.validateBody(pb, res, status, "", nil)
+			}
+			if !valid() {
+				return
+			}
 			ctx.SetStatus(status)
 		}
 	})))
+
+	if configOf(api).RecoverPanics {
+		handle = recoverHandler(api, &op, handle)
+	}
+
+	autoHead := op.Method == http.MethodGet && configOf(api).AutoHead
+	if autoHead {
+		// Some routers send `HEAD` requests to `GET` handlers, so the handler
+		// needs to support both.
+		handle = headHandler(handle)
+	}
+	if cors != nil {
+		handle = corsHandler(cors, handle)
+	}
+	if collector := configOf(api).Metrics; collector != nil {
+		handle = metricsHandler(collector, &op, handle)
+	}
+	if tracer := configOf(api).Tracer; tracer != nil {
+		handle = traceHandler(tracer, &op, handle)
+	}
+	a.Handle(&op, matchRoute(handle))
+	oapi.addRoute(op.Path, op.Method)
+	if autoHead {
+		registerHead(api, &op, handle)
+	}
+	if preflight {
+		registerPreflight(api, cors, op.Path)
+	}
 }
 
 // AutoRegister auto-detects operation registration methods and registers them
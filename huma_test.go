@@ -1,7 +1,10 @@
 package huma_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +14,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -60,16 +64,98 @@ func (UUID) Schema(r huma.Registry) *huma.Schema {
 	return &huma.Schema{Type: huma.TypeString, Format: "uuid"}
 }
 
+// UserID is a custom string type for testing registered param decoders which
+// override the built-in parsing.
+type UserID string
+
+func (u *UserID) UnmarshalText(text []byte) error {
+	if !strings.HasPrefix(string(text), "u_") {
+		return errors.New("user ID must start with u_")
+	}
+	*u = UserID(strings.TrimPrefix(string(text), "u_"))
+	return nil
+}
+
+// Color is a string enum which validates its values.
+type Color string
+
+func (c *Color) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red", "blue":
+		*c = Color(text)
+		return nil
+	}
+	return errors.New("unknown color")
+}
+
+// Date is a custom struct type for testing `encoding.TextUnmarshaler` params.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func (d *Date) UnmarshalText(text []byte) error {
+	t, err := time.Parse("2006-01-02", string(text))
+	if err != nil {
+		return err
+	}
+	d.Year, d.Month, d.Day = t.Date()
+	return nil
+}
+
+// Priority is a custom type for testing registered param decoders.
+type Priority int
+
+func parsePriority(value string) (Priority, error) {
+	switch value {
+	case "low":
+		return 1, nil
+	case "high":
+		return 2, nil
+	}
+	return 0, errors.New("unknown priority")
+}
+
+// PaymentMethod is a polymorphic type for testing oneOf with a discriminator.
+type PaymentMethod interface {
+	isPaymentMethod()
+}
+
+type PaymentCard struct {
+	Type   string `json:"type"`
+	Number string `json:"number" minLength:"4"`
+}
+
+func (PaymentCard) isPaymentMethod() {}
+
+type PaymentBank struct {
+	Type    string `json:"type"`
+	Account string `json:"account"`
+}
+
+func (*PaymentBank) isPaymentMethod() {}
+
+// gzipString returns the gzip compressed value for use as a request body.
+func gzipString(value string) string {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	w.Write([]byte(value))
+	w.Close()
+	return buf.String()
+}
+
 func TestFeatures(t *testing.T) {
 	for _, feature := range []struct {
-		Name         string
-		Transformers []huma.Transformer
-		Register     func(t *testing.T, api huma.API)
-		Method       string
-		URL          string
-		Headers      map[string]string
-		Body         string
-		Assert       func(t *testing.T, resp *httptest.ResponseRecorder)
+		Name            string
+		Transformers    []huma.Transformer
+		RequestDecoders map[string]huma.RequestDecoder
+		Register        func(t *testing.T, api huma.API)
+		Method          string
+		URL             string
+		Headers         map[string]string
+		Body            string
+		Assert          func(t *testing.T, resp *httptest.ResponseRecorder)
 	}{
 		{
 			Name: "middleware",
@@ -306,6 +392,235 @@ func TestFeatures(t *testing.T) {
 				assert.Equal(t, http.StatusInternalServerError, resp.Code)
 			},
 		},
+		{
+			Name: "params-pointer",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/test-params",
+				}, func(ctx context.Context, input *struct {
+					QueryInt     *int         `query:"int" minimum:"0"`
+					QueryMissing *int         `query:"missing"`
+					QueryBool    *bool        `query:"bool"`
+					QueryBefore  *time.Time   `query:"before"`
+					QueryUUID    *UUID        `query:"uuid"`
+					QueryStrings *[]string    `query:"strings"`
+					HeaderString *string      `header:"String"`
+					CookieFull   *http.Cookie `cookie:"one"`
+					CookieNone   *http.Cookie `cookie:"two"`
+				}) (*struct{}, error) {
+					if assert.NotNil(t, input.QueryInt) {
+						// Zero values are distinguishable from missing values.
+						assert.Equal(t, 0, *input.QueryInt)
+					}
+					assert.Nil(t, input.QueryMissing)
+					if assert.NotNil(t, input.QueryBool) {
+						assert.False(t, *input.QueryBool)
+					}
+					if assert.NotNil(t, input.QueryBefore) {
+						assert.True(t, input.QueryBefore.Equal(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)))
+					}
+					if assert.NotNil(t, input.QueryUUID) {
+						assert.Equal(t, uuid.MustParse("fba4f46b-4539-4d19-8e3f-a0e629a243b5"), input.QueryUUID.UUID)
+					}
+					if assert.NotNil(t, input.QueryStrings) {
+						assert.Equal(t, []string{"foo", "bar"}, *input.QueryStrings)
+					}
+					assert.Nil(t, input.HeaderString)
+					if assert.NotNil(t, input.CookieFull) {
+						assert.Equal(t, "foo", input.CookieFull.Value)
+					}
+					assert.Nil(t, input.CookieNone)
+					return nil, nil
+				})
+
+				// Docs use the element type rather than a nullable type.
+				params := api.OpenAPI().Paths["/test-params"].Get.Parameters
+				assert.Equal(t, "integer", params[0].Schema.Type)
+				assert.False(t, params[0].Schema.Nullable)
+				assert.Equal(t, "string", params[7].Schema.Type)
+			},
+			Method: http.MethodGet,
+			URL:    "/test-params?int=0&bool=false&before=2023-01-01T12:00:00Z&uuid=fba4f46b-4539-4d19-8e3f-a0e629a243b5&strings=foo,bar",
+			Headers: map[string]string{
+				"cookie": "one=foo",
+			},
+		},
+		{
+			Name: "params-pointer-error",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/test-params",
+				}, func(ctx context.Context, input *struct {
+					QueryInt *int `query:"int" minimum:"1"`
+					QueryReq *int `query:"req" required:"true"`
+				}) (*struct{}, error) {
+					return nil, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/test-params?int=0",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"location":"query.int"`)
+				assert.Contains(t, resp.Body.String(), "required query parameter is missing")
+			},
+		},
+		{
+			Name: "params-style-explode",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/test-params/{ids}",
+				}, func(ctx context.Context, input *struct {
+					PathIDs     []int     `path:"ids" style:"simple"`
+					Tags        []string  `query:"tag" explode:"true"`
+					Nums        []int     `query:"num" explode:"true" maxItems:"3"`
+					Spaces      []string  `query:"spaces" style:"spaceDelimited"`
+					Pipes       []float64 `query:"pipes" style:"pipeDelimited"`
+					PipeDefault []string  `query:"pipedef" style:"pipeDelimited" default:"a,b"`
+					Missing     []string  `query:"missing" explode:"true"`
+					Headers     []string  `header:"X-Values"`
+				}) (*struct{}, error) {
+					assert.Equal(t, []int{1, 2}, input.PathIDs)
+					assert.Equal(t, []string{"a,b", "c"}, input.Tags)
+					assert.Equal(t, []int{1, 2}, input.Nums)
+					assert.Equal(t, []string{"x", "y"}, input.Spaces)
+					assert.Equal(t, []float64{1.5, 2.5}, input.Pipes)
+					assert.Equal(t, []string{"a", "b"}, input.PipeDefault)
+					assert.Nil(t, input.Missing)
+					assert.Equal(t, []string{"h1", "h2"}, input.Headers)
+					return nil, nil
+				})
+
+				params := api.OpenAPI().Paths["/test-params/{ids}"].Get.Parameters
+				assert.Equal(t, "simple", params[0].Style)
+				assert.Nil(t, params[0].Explode)
+				assert.Empty(t, params[1].Style)
+				assert.True(t, *params[1].Explode)
+				assert.Equal(t, "spaceDelimited", params[3].Style)
+				assert.False(t, *params[3].Explode)
+				assert.Equal(t, "pipeDelimited", params[4].Style)
+			},
+			Method: http.MethodGet,
+			URL:    "/test-params/1,2?tag=a,b&tag=c&num=1&num=2&spaces=x+y&pipes=1.5|2.5",
+			Headers: map[string]string{
+				"X-Values": "h1,h2",
+			},
+		},
+		{
+			Name: "params-style-explode-error",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/test-params",
+				}, func(ctx context.Context, input *struct {
+					Nums []int `query:"num" explode:"true" maxItems:"2"`
+				}) (*struct{}, error) {
+					return nil, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/test-params?num=1&num=2&num=bad",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), "invalid integer")
+				assert.Contains(t, resp.Body.String(), "query.num")
+			},
+		},
+		{
+			Name: "params-deep-object",
+			Register: func(t *testing.T, api huma.API) {
+				type Filter struct {
+					Status string   `json:"status" enum:"open,closed"`
+					Owner  string   `json:"owner,omitempty"`
+					Limit  int      `json:"limit,omitempty" minimum:"1" default:"10"`
+					Tags   []string `json:"tags,omitempty"`
+					Sub    struct {
+						Active bool `json:"active"`
+					} `json:"sub,omitempty"`
+				}
+
+				huma.Register(api, huma.Operation{
+					OperationID: "deep",
+					Method:      http.MethodGet,
+					Path:        "/test-params",
+				}, func(ctx context.Context, input *struct {
+					Filter  Filter            `query:"filter"`
+					Labels  map[string]int    `query:"labels"`
+					Missing *Filter           `query:"missing"`
+					Extra   map[string]string `query:"extra" style:"deepObject"`
+				}) (*struct{}, error) {
+					assert.Equal(t, "open", input.Filter.Status)
+					assert.Equal(t, "bob", input.Filter.Owner)
+					assert.Equal(t, 10, input.Filter.Limit)
+					assert.Equal(t, []string{"a", "b"}, input.Filter.Tags)
+					assert.True(t, input.Filter.Sub.Active)
+					assert.Equal(t, map[string]int{"x": 1, "y": 2}, input.Labels)
+					assert.Nil(t, input.Missing)
+					assert.Nil(t, input.Extra)
+					return nil, nil
+				})
+
+				params := api.OpenAPI().Paths["/test-params"].Get.Parameters
+				assert.Equal(t, "deepObject", params[0].Style)
+				assert.True(t, *params[0].Explode)
+				assert.Equal(t, "#/components/schemas/Filter", params[0].Schema.Ref)
+				assert.Equal(t, "deepObject", params[1].Style)
+			},
+			Method: http.MethodGet,
+			URL:    "/test-params?filter[status]=open&filter[owner]=bob&filter[tags][]=a&filter[tags][]=b&filter[sub][active]=true&labels[x]=1&labels[y]=2",
+		},
+		{
+			Name: "params-deep-object-error",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					OperationID: "deep-error",
+					Method:      http.MethodGet,
+					Path:        "/test-params",
+				}, func(ctx context.Context, input *struct {
+					Filter struct {
+						Status string `json:"status" enum:"open,closed"`
+						Limit  int    `json:"limit,omitempty"`
+					} `query:"filter"`
+					Required map[string]string `query:"required" required:"true"`
+				}) (*struct{}, error) {
+					return nil, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/test-params?filter[status]=bad&filter[limit]=nope",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"location":"query.filter.limit"`)
+				assert.Contains(t, resp.Body.String(), "invalid integer")
+				assert.Contains(t, resp.Body.String(), `"location":"query.required"`)
+			},
+		},
+		{
+			Name: "params-deep-object-validation",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					OperationID: "deep-validation",
+					Method:      http.MethodGet,
+					Path:        "/test-params",
+				}, func(ctx context.Context, input *struct {
+					Filter struct {
+						Status string `json:"status" enum:"open,closed"`
+					} `query:"filter"`
+				}) (*struct{}, error) {
+					return nil, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/test-params?filter[status]=bad&filter[unknown]=1",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"location":"query.filter.status"`)
+				assert.Contains(t, resp.Body.String(), `"location":"query.filter.unknown"`)
+			},
+		},
 		{
 			Name: "param-bypass-validation",
 			Register: func(t *testing.T, api huma.API) {
@@ -466,6 +781,111 @@ func TestFeatures(t *testing.T) {
 				assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
 			},
 		},
+		{
+			Name:            "request-body-gzip",
+			RequestDecoders: huma.DefaultRequestDecoders,
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodPut,
+					Path:   "/body",
+				}, func(ctx context.Context, input *struct {
+					Body struct {
+						Name string `json:"name"`
+					}
+				}) (*struct{}, error) {
+					assert.Equal(t, "foo", input.Body.Name)
+					return nil, nil
+				})
+
+				// Supported encodings are documented.
+				params := api.OpenAPI().Paths["/body"].Put.Parameters
+				if assert.Len(t, params, 1) {
+					assert.Equal(t, "Content-Encoding", params[0].Name)
+					assert.Equal(t, []any{"deflate", "gzip"}, params[0].Schema.Enum)
+				}
+			},
+			Method:  http.MethodPut,
+			URL:     "/body",
+			Headers: map[string]string{"Content-Encoding": "gzip"},
+			Body:    gzipString(`{"name": "foo"}`),
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, resp.Code)
+			},
+		},
+		{
+			Name:            "request-body-gzip-too-large",
+			RequestDecoders: huma.DefaultRequestDecoders,
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method:       http.MethodPut,
+					Path:         "/body",
+					MaxBodyBytes: 100,
+				}, func(ctx context.Context, input *struct {
+					Body struct {
+						Name string `json:"name"`
+					}
+				}) (*struct{}, error) {
+					return nil, nil
+				})
+			},
+			Method:  http.MethodPut,
+			URL:     "/body",
+			Headers: map[string]string{"Content-Encoding": "gzip"},
+			// Compresses to far less than the limit, but expands beyond it.
+			Body: gzipString(`{"name": "` + strings.Repeat("a", 1000) + `"}`),
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+			},
+		},
+		{
+			Name:            "request-body-gzip-skip",
+			RequestDecoders: huma.DefaultRequestDecoders,
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method:             http.MethodPut,
+					Path:               "/body",
+					SkipDecompressBody: true,
+				}, func(ctx context.Context, input *struct {
+					Encoding string `header:"Content-Encoding"`
+					RawBody  []byte
+				}) (*struct{}, error) {
+					assert.Equal(t, "gzip", input.Encoding)
+					assert.Equal(t, gzipString("hello"), string(input.RawBody))
+					return nil, nil
+				})
+			},
+			Method:  http.MethodPut,
+			URL:     "/body",
+			Headers: map[string]string{"Content-Encoding": "gzip"},
+			Body:    gzipString("hello"),
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, resp.Code)
+			},
+		},
+		{
+			Name:            "request-body-encoding-unsupported",
+			RequestDecoders: huma.DefaultRequestDecoders,
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodPut,
+					Path:   "/body",
+				}, func(ctx context.Context, input *struct {
+					Body struct {
+						Name string `json:"name"`
+					}
+				}) (*struct{}, error) {
+					return nil, nil
+				})
+			},
+			Method:  http.MethodPut,
+			URL:     "/body",
+			Headers: map[string]string{"Content-Encoding": "br"},
+			Body:    `{"name": "foo"}`,
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+				assert.Contains(t, resp.Body.String(), "expected one of deflate, gzip")
+			},
+		},
 		{
 			Name: "request-body-bad-json",
 			Register: func(t *testing.T, api huma.API) {
@@ -513,634 +933,1294 @@ func TestFeatures(t *testing.T) {
 			},
 		},
 		{
-			Name: "request-body-file-upload",
+			Name: "request-body-form",
 			Register: func(t *testing.T, api huma.API) {
 				huma.Register(api, huma.Operation{
-					Method: http.MethodPut,
-					Path:   "/file",
+					Method: http.MethodPost,
+					Path:   "/form",
 				}, func(ctx context.Context, input *struct {
-					RawBody []byte `contentType:"application/foo"`
+					Body struct {
+						GrantType string   `json:"grant_type" enum:"password,client_credentials"`
+						Scopes    []string `json:"scope,omitempty"`
+						Count     int      `json:"count,omitempty" default:"5"`
+						Client    struct {
+							ID     string `json:"id"`
+							Public bool   `json:"public,omitempty"`
+						} `json:"client"`
+					} `contentType:"application/x-www-form-urlencoded"`
 				}) (*struct{}, error) {
-					assert.Equal(t, `some-data`, string(input.RawBody))
+					assert.Equal(t, "password", input.Body.GrantType)
+					assert.Equal(t, []string{"read", "write,admin"}, input.Body.Scopes)
+					assert.Equal(t, 5, input.Body.Count)
+					assert.Equal(t, "abc", input.Body.Client.ID)
+					assert.True(t, input.Body.Client.Public)
 					return nil, nil
 				})
 
-				// Ensure OpenAPI spec is listed as a binary upload. This enables
-				// generated documentation to show a file upload button.
-				assert.Equal(t, "binary", api.OpenAPI().Paths["/file"].Put.RequestBody.Content["application/foo"].Schema.Format)
+				// Ensure OpenAPI spec is listed under the form content type.
+				assert.NotNil(t, api.OpenAPI().Paths["/form"].Post.RequestBody.Content["application/x-www-form-urlencoded"].Schema)
+			},
+			Method:  http.MethodPost,
+			URL:     "/form",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			Body:    "grant_type=password&scope=read&scope=write%2Cadmin&client[id]=abc&client[public]=true",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, resp.Code)
 			},
-			Method:  http.MethodPut,
-			URL:     "/file",
-			Headers: map[string]string{"Content-Type": "application/foo"},
-			Body:    `some-data`,
 		},
 		{
-			Name: "request-body-multipart-file",
+			Name: "request-body-form-error",
 			Register: func(t *testing.T, api huma.API) {
 				huma.Register(api, huma.Operation{
 					Method: http.MethodPost,
-					Path:   "/upload",
+					Path:   "/form",
 				}, func(ctx context.Context, input *struct {
-					RawBody multipart.Form
+					Body struct {
+						GrantType string `json:"grant_type" enum:"password,client_credentials"`
+						Count     int    `json:"count,omitempty"`
+					} `contentType:"application/x-www-form-urlencoded"`
 				}) (*struct{}, error) {
-					for name, fh := range input.RawBody.File {
-						for _, f := range fh {
-							r, err := f.Open()
-							require.NoError(t, err)
-
-							b, err := io.ReadAll(r)
-							require.NoError(t, err)
-
-							_ = r.Close()
-
-							assert.Equal(t, "test.txt", f.Filename)
-							assert.Equal(t, "text/plain", f.Header.Get("Content-Type"))
-							assert.Equal(t, "Hello, World!", string(b))
-						}
-						assert.Equal(t, "file", name)
-					}
 					return nil, nil
 				})
-
-				// Ensure OpenAPI spec is listed as a multipart/form-data upload with
-				// the appropriate schema.
-				mpContent := api.OpenAPI().Paths["/upload"].Post.RequestBody.Content["multipart/form-data"]
-				assert.Equal(t, "object", mpContent.Schema.Type)
-				assert.Equal(t, "binary", mpContent.Schema.Properties["filename"].Format)
 			},
 			Method:  http.MethodPost,
-			URL:     "/upload",
-			Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=SimpleBoundary"},
-			Body: `--SimpleBoundary
-Content-Disposition: form-data; name="file"; filename="test.txt"
-Content-Type: text/plain
-
-Hello, World!
---SimpleBoundary--`,
+			URL:     "/form",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Body:    "grant_type=bad&count=abc",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"location":"body.count"`)
+			},
 		},
 		{
-			Name: "request-body-multipart-files",
+			Name: "request-body-form-validation",
 			Register: func(t *testing.T, api huma.API) {
 				huma.Register(api, huma.Operation{
 					Method: http.MethodPost,
-					Path:   "/upload-files",
+					Path:   "/form",
 				}, func(ctx context.Context, input *struct {
-					RawBody multipart.Form
+					Body struct {
+						GrantType string `json:"grant_type" enum:"password,client_credentials"`
+					} `contentType:"application/x-www-form-urlencoded"`
 				}) (*struct{}, error) {
-					for name, fh := range input.RawBody.File {
-						for _, f := range fh {
-							r, err := f.Open()
-							require.NoError(t, err)
-
-							b, err := io.ReadAll(r)
-							require.NoError(t, err)
-
-							_ = r.Close()
-
-							// get the last char of name
-							index := name[len(name)-1:]
-							assert.Equal(t, "example"+index+".txt", f.Filename)
-							assert.Equal(t, "text/plain", f.Header.Get("Content-Type"))
-							assert.Equal(t, "Content of example"+index+".txt.", string(b))
-						}
+					return nil, nil
+				})
+			},
+			Method:  http.MethodPost,
+			URL:     "/form",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Body:    "grant_type=bad&unknown=1",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"location":"body.grant_type"`)
+				assert.Contains(t, resp.Body.String(), `"location":"body.unknown"`)
+			},
+		},
+		{
+			Name: "request-body-form-undocumented",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodPost,
+					Path:   "/form",
+				}, func(ctx context.Context, input *struct {
+					Body struct {
+						Name string `json:"name"`
 					}
+				}) (*struct{}, error) {
 					return nil, nil
 				})
-
-				// Ensure OpenAPI spec is listed as a multipart/form-data upload with
-				// the appropriate schema.
-				mpContent := api.OpenAPI().Paths["/upload-files"].Post.RequestBody.Content["multipart/form-data"]
-				assert.Equal(t, "object", mpContent.Schema.Type)
-				assert.Equal(t, "binary", mpContent.Schema.Properties["filename"].Format)
 			},
 			Method:  http.MethodPost,
-			URL:     "/upload-files",
-			Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=AnotherBoundary"},
-			Body: `--AnotherBoundary
-Content-Disposition: form-data; name="file1"; filename="example1.txt"
-Content-Type: text/plain
-
-Content of example1.txt.
---AnotherBoundary
-Content-Disposition: form-data; name="file2"; filename="example2.txt"
-Content-Type: text/plain
-
-Content of example2.txt.
---AnotherBoundary--`,
+			URL:     "/form",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Body:    "name=foo",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+			},
 		},
 		{
-			Name: "request-body-multipart-wrong-content-type",
+			Name: "request-body-content-types",
 			Register: func(t *testing.T, api huma.API) {
 				huma.Register(api, huma.Operation{
 					Method: http.MethodPut,
-					Path:   "/upload",
+					Path:   "/things",
 				}, func(ctx context.Context, input *struct {
-					RawBody multipart.Form
+					Body struct {
+						Name string `json:"name" minLength:"3"`
+					} `contentType:"application/vnd.acme+json, application/cbor"`
 				}) (*struct{}, error) {
 					return nil, nil
 				})
+
+				content := api.OpenAPI().Paths["/things"].Put.RequestBody.Content
+				assert.Len(t, content, 2)
+				assert.NotNil(t, content["application/vnd.acme+json"].Schema)
+				assert.NotNil(t, content["application/cbor"].Schema)
 			},
 			Method:  http.MethodPut,
-			URL:     "/upload",
-			Headers: map[string]string{"Content-Type": "wrong/type"},
-			Body:    `some-data`,
+			URL:     "/things",
+			Headers: map[string]string{"Content-Type": "application/vnd.acme+json"},
+			Body:    `{"name": "a"}`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				// Validation uses the schema documented for the custom content type.
 				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"location":"body.name"`)
 			},
 		},
 		{
-			Name: "request-body-multipart-invalid-data",
+			Name: "request-body-content-type-unsupported",
 			Register: func(t *testing.T, api huma.API) {
 				huma.Register(api, huma.Operation{
 					Method: http.MethodPut,
-					Path:   "/upload",
+					Path:   "/things",
 				}, func(ctx context.Context, input *struct {
-					RawBody multipart.Form
+					Body struct {
+						Name string `json:"name"`
+					} `contentType:"application/vnd.acme+json"`
 				}) (*struct{}, error) {
 					return nil, nil
 				})
 			},
 			Method:  http.MethodPut,
-			URL:     "/upload",
-			Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=SimpleBoundary"},
-			Body:    `invalid`,
+			URL:     "/things",
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    `{"name": "a"}`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+				assert.Contains(t, resp.Body.String(), "expected one of application/vnd.acme+json")
 			},
 		},
 		{
-			Name: "handler-error",
+			Name: "request-body-one-of",
 			Register: func(t *testing.T, api huma.API) {
+				huma.RegisterOneOf[PaymentMethod](api.OpenAPI().Components.Schemas, "type", map[string]PaymentMethod{
+					"card": PaymentCard{},
+					"bank": &PaymentBank{},
+				})
+
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/error",
-				}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
-					return nil, huma.Error403Forbidden("nope")
+					Method: http.MethodPut,
+					Path:   "/payment",
+				}, func(ctx context.Context, input *struct {
+					Body PaymentMethod
+				}) (*struct{ Body PaymentMethod }, error) {
+					bank, ok := input.Body.(*PaymentBank)
+					require.True(t, ok)
+					assert.Equal(t, "123", bank.Account)
+					return &struct{ Body PaymentMethod }{Body: PaymentCard{Type: "card", Number: "1234"}}, nil
 				})
+
+				// Ensure OpenAPI spec lists the variants with a discriminator.
+				s := api.OpenAPI().Components.Schemas.Map()["PaymentMethod"]
+				require.NotNil(t, s)
+				assert.Len(t, s.OneOf, 2)
+				assert.Equal(t, "type", s.Discriminator.PropertyName)
+				assert.Equal(t, "#/components/schemas/PaymentCard", s.Discriminator.Mapping["card"])
+				assert.Equal(t, "#/components/schemas/PaymentBank", s.Discriminator.Mapping["bank"])
+				assert.Equal(t, "#/components/schemas/PaymentMethod", api.OpenAPI().Paths["/payment"].Put.Responses["200"].Content["application/json"].Schema.Ref)
 			},
-			Method: http.MethodGet,
-			URL:    "/error",
+			Method: http.MethodPut,
+			URL:    "/payment",
+			Body:   `{"type": "bank", "account": "123"}`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusForbidden, resp.Code)
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"number":"1234"`)
 			},
 		},
 		{
-			Name: "handler-wrapped-error",
+			Name: "request-body-one-of-missing",
 			Register: func(t *testing.T, api huma.API) {
+				huma.RegisterOneOf[PaymentMethod](api.OpenAPI().Components.Schemas, "type", map[string]PaymentMethod{
+					"card": PaymentCard{},
+					"bank": &PaymentBank{},
+				})
+
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/error",
-				}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
-					return nil, fmt.Errorf("wrapped: %w", huma.Error403Forbidden("nope"))
+					Method: http.MethodPut,
+					Path:   "/payment",
+				}, func(ctx context.Context, input *struct {
+					Body PaymentMethod
+				}) (*struct{}, error) {
+					return nil, nil
 				})
 			},
-			Method: http.MethodGet,
-			URL:    "/error",
+			Method: http.MethodPut,
+			URL:    "/payment",
+			Body:   `{"number": "1234"}`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusForbidden, resp.Code)
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `expected required discriminator property type to be present`)
+				assert.NotContains(t, resp.Body.String(), "exactly one schema")
 			},
 		},
 		{
-			Name: "response-headers",
+			Name: "request-body-one-of-unknown",
 			Register: func(t *testing.T, api huma.API) {
-				type Resp struct {
-					Str   string    `header:"str"`
-					Int   int       `header:"int"`
-					Uint  uint      `header:"uint"`
-					Float float64   `header:"float"`
-					Bool  bool      `header:"bool"`
-					Date  time.Time `header:"date"`
-					Empty string    `header:"empty"`
-				}
+				huma.RegisterOneOf[PaymentMethod](api.OpenAPI().Components.Schemas, "type", map[string]PaymentMethod{
+					"card": PaymentCard{},
+					"bank": &PaymentBank{},
+				})
 
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/response-headers",
-				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
-					resp := &Resp{}
-					resp.Str = "str"
-					resp.Int = 1
-					resp.Uint = 2
-					resp.Float = 3.45
-					resp.Bool = true
-					resp.Date = time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
-					return resp, nil
+					Method: http.MethodPut,
+					Path:   "/payment",
+				}, func(ctx context.Context, input *struct {
+					Body PaymentMethod
+				}) (*struct{}, error) {
+					return nil, nil
 				})
 			},
-			Method: http.MethodGet,
-			URL:    "/response-headers",
+			Method: http.MethodPut,
+			URL:    "/payment",
+			Body:   `{"type": "cash"}`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusNoContent, resp.Code)
-				assert.Equal(t, "str", resp.Header().Get("Str"))
-				assert.Equal(t, "1", resp.Header().Get("Int"))
-				assert.Equal(t, "2", resp.Header().Get("Uint"))
-				assert.Equal(t, "3.45", resp.Header().Get("Float"))
-				assert.Equal(t, "true", resp.Header().Get("Bool"))
-				assert.Equal(t, "Sun, 01 Jan 2023 12:00:00 GMT", resp.Header().Get("Date"))
-				assert.Empty(t, resp.Header().Values("Empty"))
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `expected discriminator type to be one of \"bank, card\"`)
+				assert.NotContains(t, resp.Body.String(), "exactly one schema")
 			},
 		},
 		{
-			Name: "response-cookie",
+			Name: "request-body-one-of-invalid",
 			Register: func(t *testing.T, api huma.API) {
-				type Resp struct {
-					SetCookie http.Cookie `header:"Set-Cookie"`
-				}
+				huma.RegisterOneOf[PaymentMethod](api.OpenAPI().Components.Schemas, "type", map[string]PaymentMethod{
+					"card": PaymentCard{},
+					"bank": &PaymentBank{},
+				})
 
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/response-cookie",
-				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
-					resp := &Resp{}
-					resp.SetCookie = http.Cookie{
-						Name:  "foo",
-						Value: "bar",
-					}
-					return resp, nil
+					Method: http.MethodPut,
+					Path:   "/payment",
+				}, func(ctx context.Context, input *struct {
+					Body PaymentMethod
+				}) (*struct{}, error) {
+					return nil, nil
 				})
-
-				// `http.Cookie` should be treated as a string.
-				assert.Equal(t, "string", api.OpenAPI().Paths["/response-cookie"].Get.Responses["204"].Headers["Set-Cookie"].Schema.Type)
 			},
-			Method: http.MethodGet,
-			URL:    "/response-cookie",
+			Method: http.MethodPut,
+			URL:    "/payment",
+			Body:   `{"type": "card", "number": "1"}`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusNoContent, resp.Code)
-				assert.Equal(t, "foo=bar", resp.Header().Get("Set-Cookie"))
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"location":"body.number"`)
+				assert.NotContains(t, resp.Body.String(), "exactly one schema")
 			},
 		},
 		{
-			Name: "response-cookies",
+			Name: "request-body-file-upload",
 			Register: func(t *testing.T, api huma.API) {
-				type Resp struct {
-					SetCookie []http.Cookie `header:"Set-Cookie"`
-				}
+				huma.Register(api, huma.Operation{
+					Method: http.MethodPut,
+					Path:   "/file",
+				}, func(ctx context.Context, input *struct {
+					RawBody []byte `contentType:"application/foo"`
+				}) (*struct{}, error) {
+					assert.Equal(t, `some-data`, string(input.RawBody))
+					return nil, nil
+				})
 
+				// Ensure OpenAPI spec is listed as a binary upload. This enables
+				// generated documentation to show a file upload button.
+				assert.Equal(t, "binary", api.OpenAPI().Paths["/file"].Put.RequestBody.Content["application/foo"].Schema.Format)
+			},
+			Method:  http.MethodPut,
+			URL:     "/file",
+			Headers: map[string]string{"Content-Type": "application/foo"},
+			Body:    `some-data`,
+		},
+		{
+			Name: "request-body-stream",
+			Register: func(t *testing.T, api huma.API) {
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/response-cookies",
-				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
-					resp := &Resp{}
-					resp.SetCookie = []http.Cookie{
-						{
-							Name:  "foo",
-							Value: "bar",
-						},
-						{
-							Name:  "baz",
-							Value: "123",
-						},
-					}
-					return resp, nil
+					Method: http.MethodPut,
+					Path:   "/stream",
+				}, func(ctx context.Context, input *struct {
+					RawBody io.Reader `contentType:"application/zip"`
+				}) (*struct{}, error) {
+					b, err := io.ReadAll(input.RawBody)
+					require.NoError(t, err)
+					assert.Equal(t, "some-large-data", string(b))
+					return nil, nil
 				})
 
-				// `[]http.Cookie` should be treated as a string.
-				assert.Equal(t, "string", api.OpenAPI().Paths["/response-cookies"].Get.Responses["204"].Headers["Set-Cookie"].Schema.Type)
+				// Ensure OpenAPI spec is listed as a binary upload.
+				content := api.OpenAPI().Paths["/stream"].Put.RequestBody.Content["application/zip"]
+				assert.Equal(t, "binary", content.Schema.Format)
 			},
-			Method: http.MethodGet,
-			URL:    "/response-cookies",
+			Method: http.MethodPut,
+			URL:    "/stream",
+			Body:   "some-large-data",
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
 				assert.Equal(t, http.StatusNoContent, resp.Code)
-				assert.Equal(t, "foo=bar", resp.Header()["Set-Cookie"][0])
-				assert.Equal(t, "baz=123", resp.Header()["Set-Cookie"][1])
 			},
 		},
 		{
-			Name: "response-custom-content-type",
+			Name: "request-body-stream-too-large",
 			Register: func(t *testing.T, api huma.API) {
-				type Resp struct {
-					ContentType string `header:"Content-Type"`
-				}
-
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/response-custom-ct",
-				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
-					resp := &Resp{}
-					resp.ContentType = "application/custom-type"
-					return resp, nil
+					Method:       http.MethodPut,
+					Path:         "/stream",
+					MaxBodyBytes: 5,
+				}, func(ctx context.Context, input *struct {
+					RawBody io.Reader
+				}) (*struct{}, error) {
+					b, err := io.ReadAll(input.RawBody)
+					assert.Equal(t, "some-", string(b))
+					return nil, err
 				})
 			},
-			Method: http.MethodGet,
-			URL:    "/response-custom-ct",
+			Method: http.MethodPut,
+			URL:    "/stream",
+			Body:   "some-large-data",
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusNoContent, resp.Code)
-				assert.Equal(t, "application/custom-type", resp.Header().Get("Content-Type"))
+				assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
 			},
 		},
 		{
-			Name: "response",
+			Name: "request-body-multipart-file",
 			Register: func(t *testing.T, api huma.API) {
-				type Resp struct {
-					Body struct {
-						Greeting string `json:"greeting"`
+				huma.Register(api, huma.Operation{
+					Method: http.MethodPost,
+					Path:   "/upload",
+				}, func(ctx context.Context, input *struct {
+					RawBody multipart.Form
+				}) (*struct{}, error) {
+					for name, fh := range input.RawBody.File {
+						for _, f := range fh {
+							r, err := f.Open()
+							require.NoError(t, err)
+
+							b, err := io.ReadAll(r)
+							require.NoError(t, err)
+
+							_ = r.Close()
+
+							assert.Equal(t, "test.txt", f.Filename)
+							assert.Equal(t, "text/plain", f.Header.Get("Content-Type"))
+							assert.Equal(t, "Hello, World!", string(b))
+						}
+						assert.Equal(t, "file", name)
 					}
-				}
+					return nil, nil
+				})
+
+				// Ensure OpenAPI spec is listed as a multipart/form-data upload with
+				// the appropriate schema.
+				mpContent := api.OpenAPI().Paths["/upload"].Post.RequestBody.Content["multipart/form-data"]
+				assert.Equal(t, "object", mpContent.Schema.Type)
+				assert.Equal(t, "binary", mpContent.Schema.Properties["filename"].Format)
+			},
+			Method:  http.MethodPost,
+			URL:     "/upload",
+			Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=SimpleBoundary"},
+			Body: `--SimpleBoundary
+Content-Disposition: form-data; name="file"; filename="test.txt"
+Content-Type: text/plain
 
+Hello, World!
+--SimpleBoundary--`,
+		},
+		{
+			Name: "request-body-multipart-files",
+			Register: func(t *testing.T, api huma.API) {
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/response",
-				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
-					resp := &Resp{}
-					resp.Body.Greeting = "Hello, world!"
-					return resp, nil
+					Method: http.MethodPost,
+					Path:   "/upload-files",
+				}, func(ctx context.Context, input *struct {
+					RawBody multipart.Form
+				}) (*struct{}, error) {
+					for name, fh := range input.RawBody.File {
+						for _, f := range fh {
+							r, err := f.Open()
+							require.NoError(t, err)
+
+							b, err := io.ReadAll(r)
+							require.NoError(t, err)
+
+							_ = r.Close()
+
+							// get the last char of name
+							index := name[len(name)-1:]
+							assert.Equal(t, "example"+index+".txt", f.Filename)
+							assert.Equal(t, "text/plain", f.Header.Get("Content-Type"))
+							assert.Equal(t, "Content of example"+index+".txt.", string(b))
+						}
+					}
+					return nil, nil
 				})
+
+				// Ensure OpenAPI spec is listed as a multipart/form-data upload with
+				// the appropriate schema.
+				mpContent := api.OpenAPI().Paths["/upload-files"].Post.RequestBody.Content["multipart/form-data"]
+				assert.Equal(t, "object", mpContent.Schema.Type)
+				assert.Equal(t, "binary", mpContent.Schema.Properties["filename"].Format)
 			},
-			Method: http.MethodGet,
-			URL:    "/response",
-			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusOK, resp.Code)
-				assert.JSONEq(t, `{"$schema": "https:///schemas/RespBody.json", "greeting":"Hello, world!"}`, resp.Body.String())
-			},
+			Method:  http.MethodPost,
+			URL:     "/upload-files",
+			Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=AnotherBoundary"},
+			Body: `--AnotherBoundary
+Content-Disposition: form-data; name="file1"; filename="example1.txt"
+Content-Type: text/plain
+
+Content of example1.txt.
+--AnotherBoundary
+Content-Disposition: form-data; name="file2"; filename="example2.txt"
+Content-Type: text/plain
+
+Content of example2.txt.
+--AnotherBoundary--`,
 		},
 		{
-			Name: "response-raw",
+			Name: "request-body-multipart-wrong-content-type",
 			Register: func(t *testing.T, api huma.API) {
-				type Resp struct {
-					Body []byte
-				}
-
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/response-raw",
-				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
-					return &Resp{Body: []byte("hello")}, nil
+					Method: http.MethodPut,
+					Path:   "/upload",
+				}, func(ctx context.Context, input *struct {
+					RawBody multipart.Form
+				}) (*struct{}, error) {
+					return nil, nil
 				})
 			},
-			Method: http.MethodGet,
-			URL:    "/response-raw",
+			Method:  http.MethodPut,
+			URL:     "/upload",
+			Headers: map[string]string{"Content-Type": "wrong/type"},
+			Body:    `some-data`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusOK, resp.Code)
-				assert.Equal(t, `hello`, resp.Body.String())
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
 			},
 		},
 		{
-			Name: "response-image",
+			Name: "request-body-multipart-typed",
 			Register: func(t *testing.T, api huma.API) {
-				type Resp struct {
-					ContentType string `header:"Content-Type"`
-					Body        []byte
+				type Meta struct {
+					Title string `json:"title" minLength:"1"`
 				}
 
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/response-image",
-					Responses: map[string]*huma.Response{
-						"200": {
-							Description: "Image response",
-							Content: map[string]*huma.MediaType{
-								"image/png": {
-									Schema: &huma.Schema{Type: "string", Format: "binary"},
-								},
-							},
-						},
-					},
-				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
-					return &Resp{ContentType: "image/png", Body: []byte("abc")}, nil
+					Method: http.MethodPost,
+					Path:   "/upload",
+				}, func(ctx context.Context, input *struct {
+					RawBody huma.MultipartForm[struct {
+						Avatar huma.FormFile   `form:"avatar" contentType:"text/*" maxSize:"100" required:"true"`
+						Extras []huma.FormFile `form:"extras"`
+						Meta   Meta            `form:"meta"`
+						Count  int             `form:"count" minimum:"1"`
+						Tags   []string        `form:"tags"`
+					}]
+				}) (*struct{}, error) {
+					data := input.RawBody.Data
+					assert.True(t, data.Avatar.IsSet)
+					assert.Equal(t, "test.txt", data.Avatar.Filename)
+					assert.Equal(t, "text/plain", data.Avatar.ContentType)
+					b, err := io.ReadAll(data.Avatar)
+					require.NoError(t, err)
+					assert.Equal(t, "Hello, World!", string(b))
+					assert.Empty(t, data.Extras)
+					assert.Equal(t, "My avatar", data.Meta.Title)
+					assert.Equal(t, 5, data.Count)
+					assert.Equal(t, []string{"a", "b"}, data.Tags)
+					assert.NotNil(t, input.RawBody.Form)
+					return nil, nil
 				})
 
-				// Ensure the OpenAPI spec is correct.
-				assert.Len(t, api.OpenAPI().Paths["/response-image"].Get.Responses["200"].Content, 1)
-				assert.NotEmpty(t, "binary", api.OpenAPI().Paths["/response-image"].Get.Responses["200"].Content["image/png"])
+				// Ensure OpenAPI spec describes each part of the form.
+				mpContent := api.OpenAPI().Paths["/upload"].Post.RequestBody.Content["multipart/form-data"]
+				assert.Equal(t, "binary", mpContent.Schema.Properties["avatar"].Format)
+				assert.Equal(t, "array", mpContent.Schema.Properties["extras"].Type)
+				assert.Equal(t, "integer", mpContent.Schema.Properties["count"].Type)
+				assert.Equal(t, []string{"avatar"}, mpContent.Schema.Required)
+				assert.Equal(t, "text/*", mpContent.Encoding["avatar"].ContentType)
+				assert.Equal(t, "application/json", mpContent.Encoding["meta"].ContentType)
 			},
-			Method: http.MethodGet,
-			URL:    "/response-image",
+			Method:  http.MethodPost,
+			URL:     "/upload",
+			Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=SimpleBoundary"},
+			Body: `--SimpleBoundary
+Content-Disposition: form-data; name="avatar"; filename="test.txt"
+Content-Type: text/plain
+
+Hello, World!
+--SimpleBoundary
+Content-Disposition: form-data; name="meta"
+
+{"title": "My avatar"}
+--SimpleBoundary
+Content-Disposition: form-data; name="count"
+
+5
+--SimpleBoundary
+Content-Disposition: form-data; name="tags"
+
+a
+--SimpleBoundary
+Content-Disposition: form-data; name="tags"
+
+b
+--SimpleBoundary--`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusOK, resp.Code)
-				assert.Equal(t, `abc`, resp.Body.String())
+				assert.Equal(t, http.StatusNoContent, resp.Code)
 			},
 		},
 		{
-			Name: "response-stream",
+			Name: "request-body-multipart-typed-error",
 			Register: func(t *testing.T, api huma.API) {
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/stream",
-				}, func(ctx context.Context, input *struct{}) (*huma.StreamResponse, error) {
-					return &huma.StreamResponse{
-						Body: func(ctx huma.Context) {
-							writer := ctx.BodyWriter()
-							writer.Write([]byte("hel"))
-							writer.Write([]byte("lo"))
-						},
-					}, nil
+					Method: http.MethodPost,
+					Path:   "/upload",
+				}, func(ctx context.Context, input *struct {
+					RawBody huma.MultipartForm[struct {
+						Avatar huma.FormFile `form:"avatar" contentType:"image/png" required:"true"`
+						Doc    huma.FormFile `form:"doc" maxSize:"5"`
+						Meta   struct {
+							Title string `json:"title" minLength:"1"`
+						} `form:"meta" required:"true"`
+						Count int    `form:"count" minimum:"1"`
+						Name  string `form:"name" required:"true"`
+					}]
+				}) (*struct{}, error) {
+					return nil, nil
 				})
 			},
-			Method: http.MethodGet,
-			URL:    "/stream",
+			Method:  http.MethodPost,
+			URL:     "/upload",
+			Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=SimpleBoundary"},
+			Body: `--SimpleBoundary
+Content-Disposition: form-data; name="avatar"; filename="test.txt"
+Content-Type: text/plain
+
+Hello, World!
+--SimpleBoundary
+Content-Disposition: form-data; name="doc"; filename="doc.txt"
+Content-Type: text/plain
+
+Hello, World!
+--SimpleBoundary
+Content-Disposition: form-data; name="meta"
+
+{"title": ""}
+--SimpleBoundary
+Content-Disposition: form-data; name="count"
+
+0
+--SimpleBoundary--`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusOK, resp.Code)
-				assert.Equal(t, `hello`, resp.Body.String())
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+				body := resp.Body.String()
+				assert.Contains(t, body, "invalid content type")
+				assert.Contains(t, body, "file is too large")
+				assert.Contains(t, body, `"location":"body.meta.title"`)
+				assert.Contains(t, body, `"location":"body.count"`)
+				assert.Contains(t, body, "required form field is missing")
 			},
 		},
 		{
-			Name: "response-transform-error",
-			Transformers: []huma.Transformer{
-				func(ctx huma.Context, status string, v any) (any, error) {
-					return nil, http.ErrNotSupported
-				},
-			},
+			Name: "request-body-multipart-invalid-data",
 			Register: func(t *testing.T, api huma.API) {
-				api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
-					called := false
-					defer func() {
-						if err := recover(); err != nil {
-							// Ensure the error is the one we expect, possibly wrapped with
-							// additional info.
-							assert.ErrorIs(t, err.(error), http.ErrNotSupported)
-						}
-						called = true
-					}()
-					next(ctx)
-					assert.True(t, called)
-				})
 				huma.Register(api, huma.Operation{
-					Method: http.MethodGet,
-					Path:   "/response",
-				}, func(ctx context.Context, input *struct{}) (*struct{ Body string }, error) {
-					return &struct{ Body string }{"foo"}, nil
+					Method: http.MethodPut,
+					Path:   "/upload",
+				}, func(ctx context.Context, input *struct {
+					RawBody multipart.Form
+				}) (*struct{}, error) {
+					return nil, nil
 				})
 			},
-			Method: http.MethodGet,
-			URL:    "/response",
+			Method:  http.MethodPut,
+			URL:     "/upload",
+			Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=SimpleBoundary"},
+			Body:    `invalid`,
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				// Since the handler completed, this returns a 204, however while
-				// writing the body there is an error, so that is written as a message
-				// into the body and dumped via a panic.
-				assert.Equal(t, http.StatusOK, resp.Code)
-				assert.Equal(t, `error transforming response`, resp.Body.String())
+				assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
 			},
 		},
 		{
-			Name: "response-marshal-error",
+			Name: "handler-error",
 			Register: func(t *testing.T, api huma.API) {
-				type Resp struct {
-					Body struct {
-						Greeting any `json:"greeting"`
-					}
-				}
-
 				huma.Register(api, huma.Operation{
 					Method: http.MethodGet,
-					Path:   "/response",
+					Path:   "/error",
+				}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+					return nil, huma.Error403Forbidden("nope")
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/error",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			Name: "handler-wrapped-error",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/error",
+				}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+					return nil, fmt.Errorf("wrapped: %w", huma.Error403Forbidden("nope"))
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/error",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			Name: "response-headers",
+			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					Str   string    `header:"str"`
+					Int   int       `header:"int"`
+					Uint  uint      `header:"uint"`
+					Float float64   `header:"float"`
+					Bool  bool      `header:"bool"`
+					Date  time.Time `header:"date"`
+					Empty string    `header:"empty"`
+				}
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/response-headers",
 				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
 					resp := &Resp{}
-					resp.Body.Greeting = func() {}
+					resp.Str = "str"
+					resp.Int = 1
+					resp.Uint = 2
+					resp.Float = 3.45
+					resp.Bool = true
+					resp.Date = time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
 					return resp, nil
 				})
 			},
 			Method: http.MethodGet,
-			URL:    "/response",
+			URL:    "/response-headers",
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusOK, resp.Code)
-				assert.Equal(t, `error marshaling response`, resp.Body.String())
+				assert.Equal(t, http.StatusNoContent, resp.Code)
+				assert.Equal(t, "str", resp.Header().Get("Str"))
+				assert.Equal(t, "1", resp.Header().Get("Int"))
+				assert.Equal(t, "2", resp.Header().Get("Uint"))
+				assert.Equal(t, "3.45", resp.Header().Get("Float"))
+				assert.Equal(t, "true", resp.Header().Get("Bool"))
+				assert.Equal(t, "Sun, 01 Jan 2023 12:00:00 GMT", resp.Header().Get("Date"))
+				assert.Empty(t, resp.Header().Values("Empty"))
 			},
 		},
 		{
-			Name: "dynamic-status",
+			Name: "response-cookie",
 			Register: func(t *testing.T, api huma.API) {
 				type Resp struct {
-					Status int
+					SetCookie http.Cookie `header:"Set-Cookie"`
 				}
 
 				huma.Register(api, huma.Operation{
 					Method: http.MethodGet,
-					Path:   "/status",
+					Path:   "/response-cookie",
 				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
 					resp := &Resp{}
-					resp.Status = 256
+					resp.SetCookie = http.Cookie{
+						Name:  "foo",
+						Value: "bar",
+					}
 					return resp, nil
 				})
+
+				// `http.Cookie` should be treated as a string.
+				assert.Equal(t, "string", api.OpenAPI().Paths["/response-cookie"].Get.Responses["204"].Headers["Set-Cookie"].Schema.Type)
 			},
 			Method: http.MethodGet,
-			URL:    "/status",
+			URL:    "/response-cookie",
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
-				assert.Equal(t, 256, resp.Code)
+				assert.Equal(t, http.StatusNoContent, resp.Code)
+				assert.Equal(t, "foo=bar", resp.Header().Get("Set-Cookie"))
 			},
 		},
 		{
-			// Simulate a request with a body that came from another call, which
-			// includes the `$schema` field. It should be allowed to be passed
-			// to the new operation as input without modification.
-			Name: "round-trip-schema-field",
+			Name: "response-cookies",
 			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					SetCookie []http.Cookie `header:"Set-Cookie"`
+				}
+
 				huma.Register(api, huma.Operation{
-					Method: http.MethodPut,
-					Path:   "/round-trip",
-				}, func(ctx context.Context, input *struct {
-					Body struct {
-						Name string `json:"name"`
+					Method: http.MethodGet,
+					Path:   "/response-cookies",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					resp := &Resp{}
+					resp.SetCookie = []http.Cookie{
+						{
+							Name:  "foo",
+							Value: "bar",
+						},
+						{
+							Name:  "baz",
+							Value: "123",
+						},
 					}
-				}) (*struct{}, error) {
-					return nil, nil
+					return resp, nil
 				})
+
+				// `[]http.Cookie` should be treated as a string.
+				assert.Equal(t, "string", api.OpenAPI().Paths["/response-cookies"].Get.Responses["204"].Headers["Set-Cookie"].Schema.Type)
+			},
+			Method: http.MethodGet,
+			URL:    "/response-cookies",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, resp.Code)
+				assert.Equal(t, "foo=bar", resp.Header()["Set-Cookie"][0])
+				assert.Equal(t, "baz=123", resp.Header()["Set-Cookie"][1])
 			},
-			Method: http.MethodPut,
-			URL:    "/round-trip",
-			Body:   `{"$schema": "...", "name": "foo"}`,
 		},
 		{
-			Name: "recursive schema",
+			Name: "response-custom-content-type",
+			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					ContentType string `header:"Content-Type"`
+				}
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/response-custom-ct",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					resp := &Resp{}
+					resp.ContentType = "application/custom-type"
+					return resp, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/response-custom-ct",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, resp.Code)
+				assert.Equal(t, "application/custom-type", resp.Header().Get("Content-Type"))
+			},
+		},
+		{
+			Name: "response",
 			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					Body struct {
+						Greeting string `json:"greeting"`
+					}
+				}
+
 				huma.Register(api, huma.Operation{
-					Method: http.MethodPost,
-					Path:   "/recursive-schema",
-				}, func(ctx context.Context, input *struct {
-					Body Node
-				}) (*struct {
-					Body Node
-				}, error) {
-					return input, nil
+					Method: http.MethodGet,
+					Path:   "/response",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					resp := &Resp{}
+					resp.Body.Greeting = "Hello, world!"
+					return resp, nil
 				})
 			},
-			Method: http.MethodPost,
-			URL:    "/recursive-schema",
-			Body:   `{"name": "root", "nodes": [{"name": "child"}], "left": {"name": "left"}, "named": {"child1": {"name": "child1"}}}`,
+			Method: http.MethodGet,
+			URL:    "/response",
 			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
 				assert.Equal(t, http.StatusOK, resp.Code)
-				assert.JSONEq(t, `{"name": "root", "nodes": [{"name": "child"}], "left": {"name": "left"}, "named": {"child1": {"name": "child1"}}}`, resp.Body.String())
+				assert.JSONEq(t, `{"$schema": "https:///schemas/RespBody.json", "greeting":"Hello, world!"}`, resp.Body.String())
 			},
 		},
 		{
-			Name: "one-of input",
+			Name: "response-statuses",
 			Register: func(t *testing.T, api huma.API) {
-				// Step 1: create a custom schema
-				customSchema := &huma.Schema{
-					OneOf: []*huma.Schema{
-						{
-							Type: huma.TypeObject,
-							Properties: map[string]*huma.Schema{
-								"foo": {Type: huma.TypeString},
-							},
-						},
-						{
-							Type: huma.TypeArray,
-							Items: &huma.Schema{
-								Type: huma.TypeObject,
-								Properties: map[string]*huma.Schema{
-									"foo": {Type: huma.TypeString},
-								},
-							},
-						},
-					},
+				type Job struct {
+					ID string `json:"id"`
+				}
+
+				type Resp struct {
+					Status   int    `enum:"200,201,202,207"`
+					Location string `header:"Location" status:"201"`
+					Body     struct {
+						Name string `json:"name"`
+					} `status:"200,201"`
+					Job  *Job  `status:"202"`
+					Jobs []Job `status:"207"`
 				}
-				customSchema.PrecomputeMessages()
 
 				huma.Register(api, huma.Operation{
-					Method: http.MethodPut,
-					Path:   "/one-of",
-					// Step 2: register an operation with a custom schema
-					RequestBody: &huma.RequestBody{
-						Required: true,
-						Content: map[string]*huma.MediaType{
-							"application/json": {
-								Schema: customSchema,
-							},
-						},
-					},
+					OperationID: "upsert",
+					Method:      http.MethodPut,
+					Path:        "/things/{id}",
 				}, func(ctx context.Context, input *struct {
-					// Step 3: only take in raw bytes
-					RawBody []byte
-				}) (*struct{}, error) {
-					// Step 4: determine which it is and parse it into the right type.
-					// We will check the first byte but there are other ways to do this.
-					assert.EqualValues(t, '[', input.RawBody[0])
-					var parsed []struct {
-						Foo string `json:"foo"`
+					ID string `path:"id"`
+				}) (*Resp, error) {
+					resp := &Resp{}
+					switch input.ID {
+					case "new":
+						resp.Status = http.StatusCreated
+						resp.Location = "/things/new"
+					case "async":
+						resp.Status = http.StatusAccepted
+						resp.Job = &Job{ID: "job1"}
+					case "multi":
+						resp.Status = http.StatusMultiStatus
+						resp.Jobs = []Job{{ID: "job1"}, {ID: "job2"}}
 					}
-					require.NoError(t, json.Unmarshal(input.RawBody, &parsed))
-					assert.Len(t, parsed, 2)
-					assert.Equal(t, "first", parsed[0].Foo)
-					assert.Equal(t, "second", parsed[1].Foo)
-					return nil, nil
+					resp.Body.Name = input.ID
+					return resp, nil
 				})
+
+				// Ensure each status is documented with the right model and headers.
+				responses := api.OpenAPI().Paths["/things/{id}"].Put.Responses
+				assert.NotNil(t, responses["200"].Content["application/json"].Schema)
+				assert.Nil(t, responses["200"].Headers["Location"])
+				assert.NotNil(t, responses["201"].Headers["Location"])
+				assert.Equal(t, "#/components/schemas/Job", responses["202"].Content["application/json"].Schema.Ref)
+				assert.Nil(t, responses["202"].Headers["Location"])
 			},
 			Method: http.MethodPut,
-			URL:    "/one-of",
-			Body:   `[{"foo": "first"}, {"foo": "second"}]`,
+			URL:    "/things/async",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusAccepted, resp.Code)
+				assert.Contains(t, resp.Body.String(), `"id":"job1"`)
+			},
 		},
-	} {
-		t.Run(feature.Name, func(t *testing.T) {
-			r := chi.NewRouter()
-			r.Use(Recoverer)
-			config := huma.DefaultConfig("Features Test API", "1.0.0")
-			if feature.Transformers != nil {
-				config.Transformers = append(config.Transformers, feature.Transformers...)
-			}
-			api := humatest.Wrap(t, humachi.New(r, config))
-			feature.Register(t, api)
+		{
+			Name: "response-statuses-slice",
+			Register: func(t *testing.T, api huma.API) {
+				type Thing struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				}
 
-			var body io.Reader = nil
-			if feature.Body != "" {
-				body = strings.NewReader(feature.Body)
-			}
-			req, _ := http.NewRequest(feature.Method, feature.URL, body)
-			for k, v := range feature.Headers {
-				req.Header.Set(k, v)
-			}
-			w := httptest.NewRecorder()
-			r.ServeHTTP(w, req)
-			b, _ := api.OpenAPI().YAML()
+				type Resp struct {
+					Status int     `enum:"200,202"`
+					Items  []Thing `status:"202"`
+				}
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/things",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					return &Resp{Status: http.StatusAccepted, Items: []Thing{{ID: "a", Name: "b"}}}, nil
+				})
+
+				// Fields of status body elements are not response headers.
+				responses := api.OpenAPI().Paths["/things"].Get.Responses
+				assert.Empty(t, responses["202"].Headers)
+			},
+			Method: http.MethodGet,
+			URL:    "/things",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusAccepted, resp.Code)
+				assert.Empty(t, resp.Header().Get("Id"))
+				assert.Empty(t, resp.Header().Get("Name"))
+				assert.JSONEq(t, `[{"id":"a","name":"b"}]`, resp.Body.String())
+			},
+		},
+		{
+			Name: "response-statuses-strict",
+			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					Status int `enum:"200,201"`
+				}
+
+				huma.Register(api, huma.Operation{
+					Method:       http.MethodGet,
+					Path:         "/strict",
+					StrictStatus: true,
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					return &Resp{Status: http.StatusTeapot}, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/strict",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusInternalServerError, resp.Code)
+				assert.Contains(t, resp.Body.String(), "undeclared response status 418")
+			},
+		},
+		{
+			Name: "response-statuses-strict-error",
+			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					Status int `enum:"200,201"`
+				}
+
+				huma.Register(api, huma.Operation{
+					Method:       http.MethodGet,
+					Path:         "/strict",
+					Errors:       []int{http.StatusUnprocessableEntity},
+					StrictStatus: true,
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					// Error responses are documented, but not declared by the output.
+					return &Resp{Status: http.StatusUnprocessableEntity}, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/strict",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusInternalServerError, resp.Code)
+				assert.Contains(t, resp.Body.String(), "undeclared response status 422")
+			},
+		},
+		{
+			Name: "response-item-stream",
+			Register: func(t *testing.T, api huma.API) {
+				type Item struct {
+					ID int `json:"id"`
+				}
+
+				type Resp struct {
+					Body huma.ItemStream[Item]
+				}
+
+				huma.Register(api, huma.Operation{
+					OperationID: "list-items",
+					Method:      http.MethodGet,
+					Path:        "/items",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					ch := make(chan Item)
+					go func() {
+						defer close(ch)
+						for i := 1; i <= 3; i++ {
+							ch <- Item{ID: i}
+						}
+					}()
+					return &Resp{Body: huma.ItemStreamFromChan(ch)}, nil
+				})
+
+				// Ensure the item schema is documented for each format.
+				content := api.OpenAPI().Paths["/items"].Get.Responses["200"].Content
+				assert.Equal(t, "array", content["application/json"].Schema.Type)
+				assert.Equal(t, "#/components/schemas/Item", content["application/json"].Schema.Items.Ref)
+				assert.Equal(t, "#/components/schemas/Item", content["application/x-ndjson"].Schema.Ref)
+				assert.Equal(t, "#/components/schemas/Item", content["application/jsonl"].Schema.Ref)
+			},
+			Method: http.MethodGet,
+			URL:    "/items",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+				var items []map[string]any
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &items))
+				assert.Len(t, items, 3)
+				assert.Equal(t, 3.0, items[2]["id"])
+			},
+		},
+		{
+			Name: "response-item-stream-ndjson",
+			Register: func(t *testing.T, api huma.API) {
+				type Item struct {
+					ID int `json:"id"`
+				}
+
+				type Resp struct {
+					Body huma.ItemStream[Item]
+				}
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/items",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					return &Resp{Body: func(yield func(Item) bool) {
+						for i := 1; i <= 3; i++ {
+							if !yield(Item{ID: i}) {
+								return
+							}
+						}
+					}}, nil
+				})
+			},
+			Method:  http.MethodGet,
+			URL:     "/items",
+			Headers: map[string]string{"Accept": "application/x-ndjson"},
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.Equal(t, "application/x-ndjson", resp.Header().Get("Content-Type"))
+				lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+				assert.Len(t, lines, 3)
+				assert.Contains(t, lines[0], `"id":1`)
+				assert.Contains(t, lines[2], `"id":3`)
+			},
+		},
+		{
+			Name: "response-raw",
+			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					Body []byte
+				}
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/response-raw",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					return &Resp{Body: []byte("hello")}, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/response-raw",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.Equal(t, `hello`, resp.Body.String())
+			},
+		},
+		{
+			Name: "response-image",
+			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					ContentType string `header:"Content-Type"`
+					Body        []byte
+				}
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/response-image",
+					Responses: map[string]*huma.Response{
+						"200": {
+							Description: "Image response",
+							Content: map[string]*huma.MediaType{
+								"image/png": {
+									Schema: &huma.Schema{Type: "string", Format: "binary"},
+								},
+							},
+						},
+					},
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					return &Resp{ContentType: "image/png", Body: []byte("abc")}, nil
+				})
+
+				// Ensure the OpenAPI spec is correct.
+				assert.Len(t, api.OpenAPI().Paths["/response-image"].Get.Responses["200"].Content, 1)
+				assert.NotEmpty(t, "binary", api.OpenAPI().Paths["/response-image"].Get.Responses["200"].Content["image/png"])
+			},
+			Method: http.MethodGet,
+			URL:    "/response-image",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.Equal(t, `abc`, resp.Body.String())
+			},
+		},
+		{
+			Name: "response-stream",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/stream",
+				}, func(ctx context.Context, input *struct{}) (*huma.StreamResponse, error) {
+					return &huma.StreamResponse{
+						Body: func(ctx huma.Context) {
+							writer := ctx.BodyWriter()
+							writer.Write([]byte("hel"))
+							writer.Write([]byte("lo"))
+						},
+					}, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/stream",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.Equal(t, `hello`, resp.Body.String())
+			},
+		},
+		{
+			Name: "response-transform-error",
+			Transformers: []huma.Transformer{
+				func(ctx huma.Context, status string, v any) (any, error) {
+					return nil, http.ErrNotSupported
+				},
+			},
+			Register: func(t *testing.T, api huma.API) {
+				api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+					called := false
+					defer func() {
+						if err := recover(); err != nil {
+							// Ensure the error is the one we expect, possibly wrapped with
+							// additional info.
+							assert.ErrorIs(t, err.(error), http.ErrNotSupported)
+						}
+						called = true
+					}()
+					next(ctx)
+					assert.True(t, called)
+				})
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/response",
+				}, func(ctx context.Context, input *struct{}) (*struct{ Body string }, error) {
+					return &struct{ Body string }{"foo"}, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/response",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				// Since the handler completed, this returns a 204, however while
+				// writing the body there is an error, so that is written as a message
+				// into the body and dumped via a panic.
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.Equal(t, `error transforming response`, resp.Body.String())
+			},
+		},
+		{
+			Name: "response-marshal-error",
+			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					Body struct {
+						Greeting any `json:"greeting"`
+					}
+				}
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/response",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					resp := &Resp{}
+					resp.Body.Greeting = func() {}
+					return resp, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/response",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.Equal(t, `error marshaling response`, resp.Body.String())
+			},
+		},
+		{
+			Name: "dynamic-status",
+			Register: func(t *testing.T, api huma.API) {
+				type Resp struct {
+					Status int
+				}
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodGet,
+					Path:   "/status",
+				}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+					resp := &Resp{}
+					resp.Status = 256
+					return resp, nil
+				})
+			},
+			Method: http.MethodGet,
+			URL:    "/status",
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, 256, resp.Code)
+			},
+		},
+		{
+			// Simulate a request with a body that came from another call, which
+			// includes the `$schema` field. It should be allowed to be passed
+			// to the new operation as input without modification.
+			Name: "round-trip-schema-field",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodPut,
+					Path:   "/round-trip",
+				}, func(ctx context.Context, input *struct {
+					Body struct {
+						Name string `json:"name"`
+					}
+				}) (*struct{}, error) {
+					return nil, nil
+				})
+			},
+			Method: http.MethodPut,
+			URL:    "/round-trip",
+			Body:   `{"$schema": "...", "name": "foo"}`,
+		},
+		{
+			Name: "recursive schema",
+			Register: func(t *testing.T, api huma.API) {
+				huma.Register(api, huma.Operation{
+					Method: http.MethodPost,
+					Path:   "/recursive-schema",
+				}, func(ctx context.Context, input *struct {
+					Body Node
+				}) (*struct {
+					Body Node
+				}, error) {
+					return input, nil
+				})
+			},
+			Method: http.MethodPost,
+			URL:    "/recursive-schema",
+			Body:   `{"name": "root", "nodes": [{"name": "child"}], "left": {"name": "left"}, "named": {"child1": {"name": "child1"}}}`,
+			Assert: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, resp.Code)
+				assert.JSONEq(t, `{"name": "root", "nodes": [{"name": "child"}], "left": {"name": "left"}, "named": {"child1": {"name": "child1"}}}`, resp.Body.String())
+			},
+		},
+		{
+			Name: "one-of input",
+			Register: func(t *testing.T, api huma.API) {
+				// Step 1: create a custom schema
+				customSchema := &huma.Schema{
+					OneOf: []*huma.Schema{
+						{
+							Type: huma.TypeObject,
+							Properties: map[string]*huma.Schema{
+								"foo": {Type: huma.TypeString},
+							},
+						},
+						{
+							Type: huma.TypeArray,
+							Items: &huma.Schema{
+								Type: huma.TypeObject,
+								Properties: map[string]*huma.Schema{
+									"foo": {Type: huma.TypeString},
+								},
+							},
+						},
+					},
+				}
+				customSchema.PrecomputeMessages()
+
+				huma.Register(api, huma.Operation{
+					Method: http.MethodPut,
+					Path:   "/one-of",
+					// Step 2: register an operation with a custom schema
+					RequestBody: &huma.RequestBody{
+						Required: true,
+						Content: map[string]*huma.MediaType{
+							"application/json": {
+								Schema: customSchema,
+							},
+						},
+					},
+				}, func(ctx context.Context, input *struct {
+					// Step 3: only take in raw bytes
+					RawBody []byte
+				}) (*struct{}, error) {
+					// Step 4: determine which it is and parse it into the right type.
+					// We will check the first byte but there are other ways to do this.
+					assert.EqualValues(t, '[', input.RawBody[0])
+					var parsed []struct {
+						Foo string `json:"foo"`
+					}
+					require.NoError(t, json.Unmarshal(input.RawBody, &parsed))
+					assert.Len(t, parsed, 2)
+					assert.Equal(t, "first", parsed[0].Foo)
+					assert.Equal(t, "second", parsed[1].Foo)
+					return nil, nil
+				})
+			},
+			Method: http.MethodPut,
+			URL:    "/one-of",
+			Body:   `[{"foo": "first"}, {"foo": "second"}]`,
+		},
+	} {
+		t.Run(feature.Name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.Use(Recoverer)
+			config := huma.DefaultConfig("Features Test API", "1.0.0")
+			if feature.Transformers != nil {
+				config.Transformers = append(config.Transformers, feature.Transformers...)
+			}
+			config.RequestDecoders = feature.RequestDecoders
+			api := humatest.Wrap(t, humachi.New(r, config))
+			feature.Register(t, api)
+
+			var body io.Reader = nil
+			if feature.Body != "" {
+				body = strings.NewReader(feature.Body)
+			}
+			req, _ := http.NewRequest(feature.Method, feature.URL, body)
+			for k, v := range feature.Headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			b, _ := api.OpenAPI().YAML()
 			t.Log(string(b))
 			b, _ = httputil.DumpResponse(w.Result(), true)
 			t.Log(string(b))
@@ -1151,147 +2231,873 @@ Content of example2.txt.
 				assert.Less(t, w.Code, 300, cn)
 			}
 		})
-	}
+	}
+}
+
+func TestParamDecoders(t *testing.T) {
+	_, api := humatest.New(t)
+
+	type Input struct {
+		User     UserID     `path:"user"`
+		Since    Date       `query:"since"`
+		Dates    []Date     `query:"dates"`
+		Friends  []UserID   `query:"friends" maxItems:"2"`
+		Priority *Priority  `query:"priority"`
+		Levels   []Priority `header:"X-Levels"`
+	}
+
+	// `UnmarshalText` takes precedence over built-in parsing, so string
+	// enums can validate their values.
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/colors",
+	}, func(ctx context.Context, input *struct {
+		Color Color `query:"color"`
+	}) (*struct{}, error) {
+		assert.Equal(t, Color("red"), input.Color)
+		return nil, nil
+	})
+
+	huma.RegisterParamDecoder(parsePriority)
+	t.Cleanup(func() {
+		huma.UnregisterParamDecoder[Priority]()
+	})
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/users/{user}",
+	}, func(ctx context.Context, input *Input) (*struct{}, error) {
+		assert.Equal(t, UserID("123"), input.User)
+		assert.Equal(t, Date{Year: 2024, Month: time.March, Day: 5}, input.Since)
+		assert.Equal(t, []Date{{2024, time.January, 1}, {2024, time.January, 2}}, input.Dates)
+		assert.Equal(t, []UserID{"a", "b"}, input.Friends)
+		if assert.NotNil(t, input.Priority) {
+			assert.Equal(t, Priority(2), *input.Priority)
+		}
+		assert.Equal(t, []Priority{1, 2}, input.Levels)
+		return nil, nil
+	})
+
+	// Decoded structs are documented as strings.
+	params := api.OpenAPI().Paths["/users/{user}"].Get.Parameters
+	assert.Equal(t, "string", params[1].Schema.Type)
+	assert.NotEqual(t, "deepObject", params[1].Style)
+
+	resp := api.Get("/colors?color=red")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = api.Get("/colors?color=green")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "unknown color")
+
+	resp = api.Get("/users/u_123?since=2024-03-05&dates=2024-01-01,2024-01-02&friends=u_a,u_b&priority=high", "X-Levels: low,high")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = api.Get("/users/123?since=bad&friends=u_a,u_b,u_c&priority=urgent")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "user ID must start with u_")
+	assert.Contains(t, resp.Body.String(), `"location":"query.since"`)
+	assert.Contains(t, resp.Body.String(), `"location":"query.friends"`)
+	assert.Contains(t, resp.Body.String(), "unknown priority")
+
+	// List errors report the item which failed.
+	resp = api.Get("/users/u_123?friends=u_a,b")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"value":"b"`)
+}
+
+func TestRequestDecodersDefault(t *testing.T) {
+	// Decompression is opt-in, so by default encoded bodies are passed
+	// through as sent and no `Content-Encoding` param is documented.
+	_, api := humatest.New(t, huma.DefaultConfig("Test API", "1.0.0"))
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodPut,
+		Path:   "/body",
+	}, func(ctx context.Context, input *struct {
+		RawBody []byte
+	}) (*struct{}, error) {
+		assert.Equal(t, "compressed", string(input.RawBody))
+		return nil, nil
+	})
+
+	assert.Empty(t, api.OpenAPI().Paths["/body"].Put.Parameters)
+
+	resp := api.Put("/body", "Content-Encoding: br", strings.NewReader("compressed"))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+}
+
+func TestOpenAPI(t *testing.T) {
+	r, api := humatest.New(t, huma.DefaultConfig("Features Test API", "1.0.0"))
+
+	type Resp struct {
+		Body struct {
+			Greeting string `json:"greeting"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/test",
+	}, func(ctx context.Context, input *struct{}) (*Resp, error) {
+		resp := &Resp{}
+		resp.Body.Greeting = "Hello, world"
+		return resp, nil
+	})
+
+	for _, url := range []string{
+		"/openapi.json",
+		"/openapi-3.0.json",
+		"/openapi.yaml",
+		"/openapi-3.0.yaml",
+		"/docs",
+		"/schemas/RespBody.json",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, 200, w.Code, w.Body.String())
+	}
+}
+
+type IntNot3 int
+
+func (i IntNot3) Resolve(ctx huma.Context, prefix *huma.PathBuffer) []error {
+	if i != 0 && i%3 == 0 {
+		return []error{&huma.ErrorDetail{
+			Location: prefix.String(),
+			Message:  "Value cannot be a multiple of three",
+			Value:    i,
+		}}
+	}
+	return nil
+}
+
+var _ huma.ResolverWithPath = (*IntNot3)(nil)
+
+type ExhaustiveErrorsInputBody struct {
+	Name  string  `json:"name" maxLength:"10"`
+	Count IntNot3 `json:"count" minimum:"1"`
+
+	// Having a pointer which is never loaded should not cause
+	// the tests to fail when running resolvers.
+	Ptr *IntNot3 `json:"ptr,omitempty" minimum:"1"`
+}
+
+func (b *ExhaustiveErrorsInputBody) Resolve(ctx huma.Context) []error {
+	return []error{errors.New("body resolver error")}
+}
+
+type ExhaustiveErrorsInput struct {
+	ID     IntNot3                   `path:"id" maximum:"10"`
+	Query  IntNot3                   `query:"query"`
+	Header IntNot3                   `header:"header"`
+	Body   ExhaustiveErrorsInputBody `json:"body"`
+}
+
+func (i *ExhaustiveErrorsInput) Resolve(ctx huma.Context) []error {
+	return []error{&huma.ErrorDetail{
+		Location: "path.id",
+		Message:  "input resolver error",
+		Value:    i.ID,
+	}}
+}
+
+var _ huma.Resolver = (*ExhaustiveErrorsInput)(nil)
+
+func TestExhaustiveErrors(t *testing.T) {
+	r, app := humatest.New(t, huma.DefaultConfig("Test API", "1.0.0"))
+	huma.Register(app, huma.Operation{
+		OperationID: "test",
+		Method:      http.MethodPut,
+		Path:        "/errors/{id}",
+	}, func(ctx context.Context, input *ExhaustiveErrorsInput) (*struct{}, error) {
+		return nil, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodPut, "/errors/15?query=3", strings.NewReader(`{"name": "12345678901", "count": -6}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Header", "3")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{
+		"$schema": "https:///schemas/ErrorModel.json",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "validation failed",
+		"errors": [
+			{
+				"message": "expected number <= 10",
+				"location": "path.id",
+				"value": 15
+			}, {
+				"message": "expected length <= 10",
+				"location": "body.name",
+				"value": "12345678901"
+			}, {
+				"message": "expected number >= 1",
+				"location": "body.count",
+				"value": -6
+			}, {
+				"message": "input resolver error",
+				"location": "path.id",
+				"value": 15
+			}, {
+				"message": "Value cannot be a multiple of three",
+				"location": "path.id",
+				"value": 15
+			}, {
+				"message": "Value cannot be a multiple of three",
+				"location": "query.query",
+				"value": 3
+			}, {
+				"message": "Value cannot be a multiple of three",
+				"location": "header.header",
+				"value": 3
+			}, {
+				"message": "body resolver error"
+			}, {
+				"message": "Value cannot be a multiple of three",
+				"location": "body.count",
+				"value": -6
+			}
+		]
+	}`, w.Body.String())
+}
+
+func TestResponseValidation(t *testing.T) {
+	var reported []*huma.ErrorDetail
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.ResponseValidation = huma.ResponseValidationReport
+	config.OnResponseValidationError = func(ctx huma.Context, status int, errs []*huma.ErrorDetail) {
+		assert.Equal(t, http.StatusOK, status)
+		reported = append(reported, errs...)
+	}
+	_, api := humatest.New(t, config)
+
+	type Resp struct {
+		Limit int `header:"X-Limit" minimum:"1"`
+		Body  struct {
+			Name string `json:"name" maxLength:"5"`
+		}
+	}
+
+	handler := func(ctx context.Context, input *struct {
+		Name  string `query:"name"`
+		Limit int    `query:"limit"`
+	}) (*Resp, error) {
+		resp := &Resp{Limit: input.Limit}
+		resp.Body.Name = input.Name
+		return resp, nil
+	}
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/report",
+	}, handler)
+
+	huma.Register(api, huma.Operation{
+		Method:             http.MethodGet,
+		Path:               "/error",
+		ResponseValidation: huma.ResponseValidationError,
+	}, handler)
+
+	huma.Register(api, huma.Operation{
+		Method:             http.MethodGet,
+		Path:               "/off",
+		ResponseValidation: huma.ResponseValidationOff,
+	}, handler)
+
+	resp := api.Get("/report?name=ok&limit=1")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, reported)
+
+	resp = api.Get("/report?name=too-long&limit=0")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	if assert.Len(t, reported, 2) {
+		assert.Equal(t, "header.X-Limit", reported[0].Location)
+		assert.Equal(t, "body.name", reported[1].Location)
+	}
+
+	reported = nil
+	resp = api.Get("/error?name=too-long&limit=1")
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"location":"body.name"`)
+	assert.Len(t, reported, 1)
+
+	// Error responses don't include the headers of the invalid response.
+	assert.Empty(t, resp.Header().Get("X-Limit"))
+
+	resp = api.Get("/error?name=ok&limit=2")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "2", resp.Header().Get("X-Limit"))
+
+	reported = nil
+	resp = api.Get("/off?name=too-long&limit=0")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, reported)
+}
+
+// flushRecorder signals each flush of the response.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+}
+
+func (w *flushRecorder) Flush() {
+	w.ResponseRecorder.Flush()
+	select {
+	case w.flushed <- struct{}{}:
+	default:
+	}
+}
+
+func TestItemStreamFlush(t *testing.T) {
+	_, api := humatest.New(t)
+	w := &flushRecorder{httptest.NewRecorder(), make(chan struct{}, 1)}
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/items",
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body huma.ItemStream[int] }, error) {
+		return &struct{ Body huma.ItemStream[int] }{
+			Body: func(yield func(int) bool) {
+				yield(1)
+
+				// A buffered item is flushed without waiting for the next one.
+				select {
+				case <-w.flushed:
+				case <-time.After(time.Second):
+					t.Error("item was not flushed")
+				}
+				yield(2)
+			},
+		}, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	api.Adapter().ServeHTTP(w, req)
+	assert.Equal(t, "1\n2\n", w.Body.String())
+}
+
+func TestStrictContentNegotiation(t *testing.T) {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.StrictContentNegotiation = true
+	_, api := humatest.New(t, config)
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodPut,
+		Path:   "/things",
+	}, func(ctx context.Context, input *struct {
+		Body struct {
+			Name string `json:"name"`
+		}
+	}) (*struct{ Body string }, error) {
+		return &struct{ Body string }{Body: input.Body.Name}, nil
+	})
+
+	// Both errors are documented.
+	responses := api.OpenAPI().Paths["/things"].Put.Responses
+	assert.NotNil(t, responses["406"])
+	assert.NotNil(t, responses["415"])
+
+	resp := api.Put("/things", map[string]any{"name": "foo"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = api.Put("/things", "Content-Type: application/cbor", []byte{0xa0})
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+	assert.Contains(t, resp.Body.String(), "expected one of application/json")
+
+	resp = api.Put("/things", "Accept: application/xml", map[string]any{"name": "foo"})
+	assert.Equal(t, http.StatusNotAcceptable, resp.Code)
+	assert.Contains(t, resp.Body.String(), "expected one of application/json")
+}
+
+func TestAutoHead(t *testing.T) {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.AutoHead = true
+	_, api := humatest.New(t, config)
+
+	type Resp struct {
+		ETag string `header:"ETag"`
+		Body struct {
+			Name string `json:"name"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id" maxLength:"3"`
+	}) (*Resp, error) {
+		resp := &Resp{ETag: "abc123"}
+		resp.Body.Name = input.ID
+		return resp, nil
+	})
+
+	// The HEAD operation is documented without response bodies.
+	head := api.OpenAPI().Paths["/things/{id}"].Head
+	if assert.NotNil(t, head) {
+		assert.Equal(t, "head-thing", head.OperationID)
+		assert.NotNil(t, head.Responses["200"].Headers["ETag"])
+		assert.Nil(t, head.Responses["200"].Content)
+	}
+
+	get := api.Get("/things/foo")
+	resp := api.Do(http.MethodHead, "/things/foo")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "abc123", resp.Header().Get("ETag"))
+	assert.Equal(t, strconv.Itoa(get.Body.Len()), resp.Header().Get("Content-Length"))
+	assert.Empty(t, resp.Body.String())
+
+	// Errors are sent with their status but no body.
+	resp = api.Do(http.MethodHead, "/things/toolong")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Empty(t, resp.Body.String())
+}
+
+func TestCORS(t *testing.T) {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.CORS = &huma.CORS{
+		AllowOrigins:     []string{"https://example.com", "https://*.example.org"},
+		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		ExposeHeaders:    []string{"ETag"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}
+	_, api := humatest.New(t, config)
+
+	huma.Get(api, "/things/{id}", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+	huma.Delete(api, "/things/{id}", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	// Preflight requests get the methods registered for the path.
+	resp := api.Do(http.MethodOptions, "/things/foo",
+		"Origin: https://app.example.org",
+		"Access-Control-Request-Method: DELETE",
+	)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "https://app.example.org", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, DELETE, OPTIONS", resp.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, Content-Type", resp.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", resp.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "3600", resp.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, "Origin", resp.Header().Get("Vary"))
+
+	// Preflight operations are not documented.
+	assert.Nil(t, api.OpenAPI().Paths["/things/{id}"].Options)
+
+	// Disallowed origins get no CORS headers.
+	resp = api.Do(http.MethodOptions, "/things/foo",
+		"Origin: https://evil.com",
+		"Access-Control-Request-Method: DELETE",
+	)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Methods"))
+
+	// Normal responses get CORS headers.
+	resp = api.Get("/things/foo", "Origin: https://example.com")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "https://example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "ETag", resp.Header().Get("Access-Control-Expose-Headers"))
+
+	// Plain `OPTIONS` requests get the allowed methods.
+	resp = api.Do(http.MethodOptions, "/things/foo")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "GET, DELETE, OPTIONS", resp.Header().Get("Allow"))
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+
+	// Hidden operations are included.
+	huma.Register(api, huma.Operation{
+		Method: http.MethodPost,
+		Path:   "/things/{id}",
+		Hidden: true,
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+	resp = api.Do(http.MethodOptions, "/things/foo")
+	assert.Equal(t, "GET, POST, DELETE, OPTIONS", resp.Header().Get("Allow"))
+
+	// Paths can have their own `OPTIONS` operation if it is registered first.
+	huma.Register(api, huma.Operation{
+		Method: http.MethodOptions,
+		Path:   "/custom",
+	}, func(ctx context.Context, input *struct{}) (*struct {
+		Allow string `header:"Allow"`
+	}, error) {
+		return &struct {
+			Allow string `header:"Allow"`
+		}{Allow: "custom"}, nil
+	})
+	huma.Get(api, "/custom", func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+	resp = api.Do(http.MethodOptions, "/custom")
+	assert.Equal(t, "custom", resp.Header().Get("Allow"))
+
+	assert.Panics(t, func() {
+		huma.Register(api, huma.Operation{
+			Method: http.MethodOptions,
+			Path:   "/things/{id}",
+		}, func(ctx context.Context, input *struct {
+			ID string `path:"id"`
+		}) (*struct{}, error) {
+			return nil, nil
+		})
+	})
 }
 
-func TestOpenAPI(t *testing.T) {
-	r, api := humatest.New(t, huma.DefaultConfig("Features Test API", "1.0.0"))
-
-	type Resp struct {
-		Body struct {
-			Greeting string `json:"greeting"`
-		}
+func TestCORSCredentialsAnyOrigin(t *testing.T) {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.CORS = &huma.CORS{
+		AllowOrigins:     []string{"*"},
+		AllowCredentials: true,
 	}
+	_, api := humatest.New(t, config)
 
-	huma.Register(api, huma.Operation{
-		Method: http.MethodGet,
-		Path:   "/test",
-	}, func(ctx context.Context, input *struct{}) (*Resp, error) {
-		resp := &Resp{}
-		resp.Body.Greeting = "Hello, world"
-		return resp, nil
+	assert.Panics(t, func() {
+		huma.Get(api, "/things", func(ctx context.Context, input *struct{}) (*struct{}, error) {
+			return nil, nil
+		})
 	})
+}
 
-	for _, url := range []string{
-		"/openapi.json",
-		"/openapi-3.0.json",
-		"/openapi.yaml",
-		"/openapi-3.0.yaml",
-		"/docs",
-		"/schemas/RespBody.json",
-	} {
-		req, _ := http.NewRequest(http.MethodGet, url, nil)
-		w := httptest.NewRecorder()
-		r.ServeHTTP(w, req)
+type unwrapWriter struct {
+	http.ResponseWriter
+}
 
-		assert.Equal(t, 200, w.Code, w.Body.String())
-	}
+func (w unwrapWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
-type IntNot3 int
+func TestFlushAndWriteDeadline(t *testing.T) {
+	rec := httptest.NewRecorder()
+	assert.True(t, huma.Flush(unwrapWriter{rec}))
+	assert.True(t, rec.Flushed)
+	assert.ErrorIs(t, huma.SetWriteDeadline(unwrapWriter{rec}, time.Now()), http.ErrNotSupported)
 
-func (i IntNot3) Resolve(ctx huma.Context, prefix *huma.PathBuffer) []error {
-	if i != 0 && i%3 == 0 {
-		return []error{&huma.ErrorDetail{
-			Location: prefix.String(),
-			Message:  "Value cannot be a multiple of three",
-			Value:    i,
-		}}
-	}
-	return nil
+	var buf bytes.Buffer
+	assert.False(t, huma.Flush(&buf))
+	assert.ErrorIs(t, huma.SetWriteDeadline(&buf, time.Now()), http.ErrNotSupported)
 }
 
-var _ huma.ResolverWithPath = (*IntNot3)(nil)
+type (
+	opaqueCtx     huma.Context
+	opaqueContext struct{ opaqueCtx }
+)
 
-type ExhaustiveErrorsInputBody struct {
-	Name  string  `json:"name" maxLength:"10"`
-	Count IntNot3 `json:"count" minimum:"1"`
+func TestGetRemoteAddr(t *testing.T) {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.RecoverPanics = true
+	_, api := humatest.New(t, config)
 
-	// Having a pointer which is never loaded should not cause
-	// the tests to fail when running resolvers.
-	Ptr *IntNot3 `json:"ptr,omitempty" minimum:"1"`
+	// Wrapped contexts are unwrapped to find the adapter's address.
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		next(huma.WithValue(ctx, "key", "value"))
+	})
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		ctx.SetHeader("X-Remote-Addr", huma.GetRemoteAddr(ctx))
+		ctx.SetHeader("X-Opaque-Addr", huma.GetRemoteAddr(opaqueContext{ctx}))
+		next(ctx)
+	})
+	huma.Get(api, "/", func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	resp := api.Get("/")
+	assert.Equal(t, "127.0.0.1:12345", resp.Header().Get("X-Remote-Addr"))
+	assert.Empty(t, resp.Header().Get("X-Opaque-Addr"))
 }
 
-func (b *ExhaustiveErrorsInputBody) Resolve(ctx huma.Context) []error {
-	return []error{errors.New("body resolver error")}
+func TestRouteErrorHandler(t *testing.T) {
+	_, api := humatest.New(t)
+
+	huma.Get(api, "/things/{id}", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*struct{}, error) {
+		return nil, huma.Error404NotFound("thing not found")
+	})
+	huma.Put(api, "/things/{id}", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodPost,
+		Path:   "/things/{id}",
+		Hidden: true,
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	api.Adapter().Handle(&huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/raw",
+	}, func(ctx huma.Context) {
+		ctx.BodyWriter().Write([]byte("raw"))
+	})
+
+	handler := huma.RouteErrorHandler(api, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/things/missing" {
+			// The router may reject paths which look like registered ones.
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodDelete && r.URL.Path == "/things/raw" {
+			// Registered directly on the router for a path with operations.
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		api.Adapter().ServeHTTP(w, r)
+	}))
+	do := func(method, path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(method, path, nil))
+		return resp
+	}
+
+	// Unknown methods on documented paths get a 405 with the allowed methods.
+	resp := do(http.MethodPatch, "/things/foo")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+	assert.Equal(t, "GET, HEAD, POST, PUT", resp.Header().Get("Allow"))
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+	assert.Contains(t, resp.Body.String(), "method PATCH not allowed")
+
+	// Hidden operations and `HEAD` for `GET` operations are allowed.
+	resp = do(http.MethodPost, "/things/foo")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = do(http.MethodHead, "/things/foo")
+	assert.NotEqual(t, http.StatusMethodNotAllowed, resp.Code)
+
+	// Unknown paths get a 404 using the error model.
+	resp = do(http.MethodGet, "/unknown")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+	assert.Contains(t, resp.Body.String(), "no operation found for path /unknown")
+
+	resp = do(http.MethodGet, "/things/missing")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+	assert.Contains(t, resp.Body.String(), "no operation found for path /things/missing")
+
+	// Operations and routes registered directly on the router still work,
+	// including errors returned by the handler.
+	resp = do(http.MethodGet, "/things/foo")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Contains(t, resp.Body.String(), "thing not found")
+
+	resp = do(http.MethodGet, "/raw")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "raw", resp.Body.String())
+
+	resp = do(http.MethodDelete, "/things/raw")
+	assert.Equal(t, http.StatusAccepted, resp.Code)
 }
 
-type ExhaustiveErrorsInput struct {
-	ID     IntNot3                   `path:"id" maximum:"10"`
-	Query  IntNot3                   `query:"query"`
-	Header IntNot3                   `header:"header"`
-	Body   ExhaustiveErrorsInputBody `json:"body"`
+func TestSecurity(t *testing.T) {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Components.SecuritySchemes = map[string]*huma.SecurityScheme{
+		"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		"basic":  {Type: "http", Scheme: "basic"},
+		"bearer": {Type: "http", Scheme: "bearer"},
+	}
+	config.Authenticators = map[string]huma.Authenticator{
+		"apiKey": func(ctx huma.Context, creds huma.Credentials) (any, error) {
+			if creds.Token != "key" {
+				return nil, errors.New("unknown api key")
+			}
+			return "key-user", nil
+		},
+		"basic": func(ctx huma.Context, creds huma.Credentials) (any, error) {
+			if creds.Username != "user" || creds.Password != "pass" {
+				return nil, errors.New("wrong username or password")
+			}
+			return creds.Username, nil
+		},
+		"bearer": func(ctx huma.Context, creds huma.Credentials) (any, error) {
+			if creds.Token != "token" {
+				return nil, errors.New("invalid token")
+			}
+			return "token-user", nil
+		},
+	}
+	_, api := humatest.New(t, config)
+
+	type Resp struct {
+		Body struct {
+			Principal any `json:"principal"`
+		}
+	}
+	handler := func(ctx context.Context, input *struct{}) (*Resp, error) {
+		resp := &Resp{}
+		resp.Body.Principal = huma.GetPrincipal(ctx)
+		return resp, nil
+	}
+
+	huma.Register(api, huma.Operation{
+		Method:   http.MethodGet,
+		Path:     "/basic",
+		Security: []map[string][]string{{"basic": {}}},
+	}, handler)
+	huma.Register(api, huma.Operation{
+		Method:   http.MethodGet,
+		Path:     "/either",
+		Security: []map[string][]string{{"apiKey": {}}, {"bearer": {}}},
+	}, handler)
+	huma.Register(api, huma.Operation{
+		Method:   http.MethodGet,
+		Path:     "/both",
+		Security: []map[string][]string{{"apiKey": {}, "bearer": {}}},
+	}, handler)
+	huma.Register(api, huma.Operation{
+		Method:   http.MethodGet,
+		Path:     "/optional",
+		Security: []map[string][]string{{}, {"bearer": {}}},
+	}, handler)
+
+	// Required security documents a 401 response.
+	assert.NotNil(t, api.OpenAPI().Paths["/basic"].Get.Responses["401"])
+	assert.Nil(t, api.OpenAPI().Paths["/optional"].Get.Responses["401"])
+
+	resp := api.Get("/basic")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, `Basic realm="Test API"`, resp.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, resp.Body.String(), "authentication required")
+
+	resp = api.Get("/basic", "Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte("user:wrong")))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "wrong username or password")
+
+	resp = api.Get("/basic", "Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"principal":"user"`)
+
+	// Any one requirement can be satisfied.
+	resp = api.Get("/either", "X-API-Key: key")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"principal":"key-user"`)
+
+	resp = api.Get("/either", "Authorization: Bearer token")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"principal":"token-user"`)
+
+	resp = api.Get("/either")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "Bearer", resp.Header().Get("WWW-Authenticate"))
+
+	// All schemes in a requirement must be satisfied.
+	resp = api.Get("/both", "Authorization: Bearer token")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = api.Get("/both", "Authorization: Bearer token", "X-API-Key: key")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"principal":"key-user"`)
+
+	// Optional security allows anonymous requests, but not invalid ones.
+	resp = api.Get("/optional")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"principal":null`)
+
+	resp = api.Get("/optional", "Authorization: Bearer bad")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid token")
+
+	// Schemes without an authenticator can't be enforced.
+	assert.Panics(t, func() {
+		huma.Register(api, huma.Operation{
+			Method:   http.MethodGet,
+			Path:     "/missing",
+			Security: []map[string][]string{{"oauth2": {}}},
+		}, handler)
+	})
 }
 
-func (i *ExhaustiveErrorsInput) Resolve(ctx huma.Context) []error {
-	return []error{&huma.ErrorDetail{
-		Location: "path.id",
-		Message:  "input resolver error",
-		Value:    i.ID,
-	}}
+type scopedUser struct {
+	Name   string
+	Scopes []string
 }
 
-var _ huma.Resolver = (*ExhaustiveErrorsInput)(nil)
+func (u *scopedUser) HasScope(scope string) bool {
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
 
-func TestExhaustiveErrors(t *testing.T) {
-	r, app := humatest.New(t, huma.DefaultConfig("Test API", "1.0.0"))
-	huma.Register(app, huma.Operation{
-		OperationID: "test",
-		Method:      http.MethodPut,
-		Path:        "/errors/{id}",
-	}, func(ctx context.Context, input *ExhaustiveErrorsInput) (*struct{}, error) {
+func TestSecurityScopes(t *testing.T) {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Components.SecuritySchemes = map[string]*huma.SecurityScheme{
+		"oauth2": {
+			Type: "oauth2",
+			Flows: &huma.OAuthFlows{
+				ClientCredentials: &huma.OAuthFlow{
+					TokenURL: "https://example.com/token",
+					Scopes: map[string]string{
+						"orders:read":  "Read orders",
+						"orders:write": "Write orders",
+					},
+				},
+			},
+		},
+	}
+	config.Authenticators = map[string]huma.Authenticator{
+		"oauth2": func(ctx huma.Context, creds huma.Credentials) (any, error) {
+			return &scopedUser{Name: creds.Token, Scopes: []string{"orders:read"}}, nil
+		},
+	}
+	_, api := humatest.New(t, config)
+
+	huma.Register(api, huma.Operation{
+		Method:   http.MethodGet,
+		Path:     "/orders",
+		Security: []map[string][]string{{"oauth2": {"orders:read"}}},
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+	huma.Register(api, huma.Operation{
+		Method:   http.MethodPost,
+		Path:     "/orders",
+		Security: []map[string][]string{{"oauth2": {"orders:read", "orders:write"}}},
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
 		return nil, nil
 	})
 
-	req, _ := http.NewRequest(http.MethodPut, "/errors/15?query=3", strings.NewReader(`{"name": "12345678901", "count": -6}`))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Header", "3")
-	w := httptest.NewRecorder()
-	r.ServeHTTP(w, req)
-	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
-	assert.JSONEq(t, `{
-		"$schema": "https:///schemas/ErrorModel.json",
-		"title": "Unprocessable Entity",
-		"status": 422,
-		"detail": "validation failed",
-		"errors": [
-			{
-				"message": "expected number <= 10",
-				"location": "path.id",
-				"value": 15
-			}, {
-				"message": "expected length <= 10",
-				"location": "body.name",
-				"value": "12345678901"
-			}, {
-				"message": "expected number >= 1",
-				"location": "body.count",
-				"value": -6
-			}, {
-				"message": "input resolver error",
-				"location": "path.id",
-				"value": 15
-			}, {
-				"message": "Value cannot be a multiple of three",
-				"location": "path.id",
-				"value": 15
-			}, {
-				"message": "Value cannot be a multiple of three",
-				"location": "query.query",
-				"value": 3
-			}, {
-				"message": "Value cannot be a multiple of three",
-				"location": "header.header",
-				"value": 3
-			}, {
-				"message": "body resolver error"
-			}, {
-				"message": "Value cannot be a multiple of three",
-				"location": "body.count",
-				"value": -6
-			}
-		]
-	}`, w.Body.String())
+	// Scoped requirements document a 403 response.
+	assert.NotNil(t, api.OpenAPI().Paths["/orders"].Post.Responses["403"])
+
+	resp := api.Get("/orders", "Authorization: Bearer alice")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = api.Post("/orders", "Authorization: Bearer alice")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, `Bearer error="insufficient_scope", scope="orders:write"`, resp.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, resp.Body.String(), "insufficient scope, missing orders:write")
+	assert.Contains(t, resp.Body.String(), `"value":"orders:write"`)
+
+	// Unauthenticated requests still get a 401.
+	resp = api.Post("/orders")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
 }
 
 type MyError struct {
@@ -1433,18 +3239,40 @@ func TestResolverCompositionCalledOnce(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
 }
 
-func TestParamPointerPanics(t *testing.T) {
-	// For now, we don't support these, so we panic rather than have subtle
-	// bugs that are hard to track down.
+func TestParamStylePanics(t *testing.T) {
 	_, app := humatest.New(t, huma.DefaultConfig("Test API", "1.0.0"))
 
 	assert.Panics(t, func() {
 		huma.Register(app, huma.Operation{
-			OperationID: "bug",
+			OperationID: "bad-style",
 			Method:      http.MethodGet,
-			Path:        "/bug",
+			Path:        "/bad-style",
+		}, func(ctx context.Context, input *struct {
+			Param []string `query:"param" style:"simple"`
+		}) (*struct{}, error) {
+			return nil, nil
+		})
+	})
+
+	assert.Panics(t, func() {
+		huma.Register(app, huma.Operation{
+			OperationID: "bad-style-scalar",
+			Method:      http.MethodGet,
+			Path:        "/bad-style-scalar",
+		}, func(ctx context.Context, input *struct {
+			Param string `query:"param" style:"pipeDelimited"`
+		}) (*struct{}, error) {
+			return nil, nil
+		})
+	})
+
+	assert.Panics(t, func() {
+		huma.Register(app, huma.Operation{
+			OperationID: "bad-explode",
+			Method:      http.MethodGet,
+			Path:        "/bad-explode",
 		}, func(ctx context.Context, input *struct {
-			Param *string `query:"param"`
+			Param []string `header:"param" explode:"true"`
 		}) (*struct{}, error) {
 			return nil, nil
 		})
@@ -1659,3 +3487,218 @@ func BenchmarkHandlerFunc(b *testing.B) {
 		}
 	})
 }
+
+func TestTracing(t *testing.T) {
+	recorder := humatest.NewTraceRecorder()
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Tracer = recorder
+	_, api := humatest.New(t, config)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "create-thing",
+		Method:      http.MethodPost,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID   string `path:"id" maxLength:"5"`
+		Body struct {
+			Name string `json:"name"`
+		}
+	}) (*struct{ Body string }, error) {
+		// Handler spans are children of the handler phase span.
+		_, span := recorder.Start(ctx, "custom")
+		span.End()
+		if input.Body.Name == "fail" {
+			return nil, huma.Error500InternalServerError("oops")
+		}
+		if input.Body.Name == "panic" {
+			panic("oops")
+		}
+		return &struct{ Body string }{Body: "hello"}, nil
+	})
+
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	resp := api.Post("/things/foo", "traceparent: "+parent, map[string]any{"name": "x"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	names := []string{}
+	for _, s := range recorder.Spans() {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"huma.params", "huma.body.read", "huma.body.validate", "custom", "huma.handler", "huma.transform", "huma.marshal", "POST /things/{id}"}, names)
+
+	root := recorder.Span("POST /things/{id}")
+	require.NotNil(t, root)
+	tp, _ := huma.ParseTraceParent(parent)
+	assert.Equal(t, tp.TraceID, root.TraceParent.TraceID)
+	assert.Equal(t, tp.SpanID, root.ParentID)
+	assert.Equal(t, "create-thing", root.Attributes[huma.AttrOperationID])
+	assert.Equal(t, "/things/{id}", root.Attributes[huma.AttrRoute])
+	assert.Equal(t, http.MethodPost, root.Attributes[huma.AttrMethod])
+	assert.Equal(t, http.StatusOK, root.Attributes[huma.AttrStatus])
+	assert.NoError(t, root.Err)
+
+	handler := recorder.Span("huma.handler")
+	assert.Equal(t, root.TraceParent.SpanID, handler.ParentID)
+	assert.Equal(t, handler.TraceParent.SpanID, recorder.Span("custom").ParentID)
+
+	// Validation errors record their locations.
+	recorder.Reset()
+	resp = api.Post("/things/toolong", map[string]any{"name": "x"})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	root = recorder.Span("POST /things/{id}")
+	require.NotNil(t, root)
+	assert.Equal(t, root.ParentID, [8]byte{})
+	assert.Equal(t, []string{"path.id"}, root.Attributes[huma.AttrErrorLocations])
+	assert.Equal(t, http.StatusUnprocessableEntity, root.Attributes[huma.AttrStatus])
+	assert.Error(t, root.Err)
+	assert.Nil(t, recorder.Span("huma.handler"))
+
+	// Handler errors mark the handler span as failed.
+	recorder.Reset()
+	resp = api.Post("/things/foo", map[string]any{"name": "fail"})
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Error(t, recorder.Span("huma.handler").Err)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Span("POST /things/{id}").Attributes[huma.AttrStatus])
+
+	// Panics still end the spans and mark them as failed.
+	recorder.Reset()
+	assert.PanicsWithValue(t, "oops", func() {
+		api.Post("/things/foo", map[string]any{"name": "panic"})
+	})
+	var pe *huma.PanicError
+	assert.ErrorAs(t, recorder.Span("huma.handler").Err, &pe)
+	root = recorder.Span("POST /things/{id}")
+	require.NotNil(t, root)
+	assert.ErrorAs(t, root.Err, &pe)
+	assert.Equal(t, "oops", pe.Value)
+	assert.Equal(t, http.StatusInternalServerError, root.Attributes[huma.AttrStatus])
+}
+
+func TestTraceParent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tp, ok := huma.ParseTraceParent(value)
+	assert.True(t, ok)
+	assert.True(t, tp.Sampled())
+	assert.Equal(t, value, tp.String())
+
+	child := tp.Child()
+	assert.Equal(t, tp.TraceID, child.TraceID)
+	assert.NotEqual(t, tp.SpanID, child.SpanID)
+
+	_, ok = huma.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.True(t, ok)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g",
+	} {
+		_, ok = huma.ParseTraceParent(invalid)
+		assert.False(t, ok, invalid)
+	}
+
+	ctx := huma.WithTraceParent(context.Background(), tp)
+	got, ok := huma.GetTraceParent(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, tp, got)
+
+	assert.True(t, huma.NewTraceParent().Sampled())
+}
+
+type panicInput struct {
+	Handler  bool `query:"handler-panic"`
+	Resolver bool `query:"resolver-panic"`
+}
+
+func (i *panicInput) Resolve(ctx huma.Context) []error {
+	if i.Resolver {
+		panic("resolver panic")
+	}
+	return nil
+}
+
+func TestRecoverPanics(t *testing.T) {
+	var reported []*huma.PanicError
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.RecoverPanics = true
+	config.OnPanic = func(ctx huma.Context, err *huma.PanicError) {
+		reported = append(reported, err)
+	}
+	config.Transformers = append(config.Transformers, func(ctx huma.Context, status string, v any) (any, error) {
+		if ctx.Query("transform-panic") != "" && status == "200" {
+			panic("transformer panic")
+		}
+		return v, nil
+	})
+	_, api := humatest.New(t, config)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-panic",
+		Method:      http.MethodGet,
+		Path:        "/panic",
+	}, func(ctx context.Context, input *panicInput) (*struct {
+		ETag     string `header:"ETag"`
+		Location string `header:"Location"`
+		Body     string
+	}, error) {
+		if input.Handler {
+			panic(errors.New("handler panic"))
+		}
+		return &struct {
+			ETag     string `header:"ETag"`
+			Location string `header:"Location"`
+			Body     string
+		}{ETag: `"abc"`, Location: "/panic/1", Body: "hello"}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/stream",
+	}, func(ctx context.Context, input *struct{}) (*huma.StreamResponse, error) {
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				ctx.SetStatus(http.StatusOK)
+				ctx.BodyWriter().Write([]byte("partial"))
+				panic("stream panic")
+			},
+		}, nil
+	})
+
+	for _, query := range []string{"handler-panic", "resolver-panic", "transform-panic"} {
+		reported = nil
+		resp := api.Get("/panic?" + query + "=true")
+		assert.Equal(t, http.StatusInternalServerError, resp.Code, query)
+		assert.Contains(t, resp.Header().Get("Content-Type"), "application/problem+json")
+		assert.Contains(t, resp.Body.String(), "internal server error")
+		assert.NotContains(t, resp.Body.String(), "panic")
+		assert.Empty(t, resp.Header().Get("ETag"), query)
+		assert.Empty(t, resp.Header().Get("Location"), query)
+		if assert.Len(t, reported, 1) {
+			assert.Equal(t, "get-panic", reported[0].Operation.OperationID)
+			assert.NotEmpty(t, reported[0].Stack)
+		}
+	}
+
+	// Panics with errors can be unwrapped.
+	reported = nil
+	api.Get("/panic?handler-panic=true")
+	require.Len(t, reported, 1)
+	assert.Equal(t, "panic: handler panic", reported[0].Error())
+	assert.EqualError(t, errors.Unwrap(reported[0]), "handler panic")
+
+	// Responses which were already started are left as is.
+	reported = nil
+	resp := api.Get("/stream")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "partial", resp.Body.String())
+	assert.Len(t, reported, 1)
+
+	resp = api.Get("/panic")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"abc"`, resp.Header().Get("ETag"))
+	assert.Equal(t, "/panic/1", resp.Header().Get("Location"))
+}
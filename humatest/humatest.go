@@ -113,6 +113,14 @@ type testAPI struct {
 	tb TB
 }
 
+// Config returns the configuration of the wrapped API.
+func (a *testAPI) Config() huma.Config {
+	if p, ok := a.API.(huma.ConfigProvider); ok {
+		return p.Config()
+	}
+	return huma.Config{}
+}
+
 func (a *testAPI) Do(method, path string, args ...any) *httptest.ResponseRecorder {
 	a.tb.Helper()
 	var b io.Reader
@@ -0,0 +1,114 @@
+package humatest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// RecordedSpan is a finished span recorded by a `TraceRecorder`.
+type RecordedSpan struct {
+	Name string
+
+	// TraceParent identifies the span and its trace.
+	TraceParent huma.TraceParent
+
+	// ParentID is the span ID of the parent span, or zero for spans which
+	// started a new trace.
+	ParentID [8]byte
+
+	Attributes map[string]any
+	Err        error
+	Start      time.Time
+	End        time.Time
+}
+
+// TraceRecorder is an in-memory `huma.Tracer` which records finished spans
+// for tests.
+//
+//	recorder := humatest.NewTraceRecorder()
+//	config := huma.DefaultConfig("My API", "1.0.0")
+//	config.Tracer = recorder
+//	_, api := humatest.New(t, config)
+type TraceRecorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+var _ huma.Tracer = (*TraceRecorder)(nil)
+
+// NewTraceRecorder creates a new trace recorder.
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{}
+}
+
+// Start a new span, as a child of the trace parent in the context if there is
+// one.
+func (r *TraceRecorder) Start(ctx context.Context, name string, attrs ...huma.Attribute) (context.Context, huma.Span) {
+	s := &recordingSpan{
+		recorder: r,
+		span: RecordedSpan{
+			Name:       name,
+			Attributes: map[string]any{},
+			Start:      time.Now(),
+		},
+	}
+	if parent, ok := huma.GetTraceParent(ctx); ok {
+		s.span.TraceParent = parent.Child()
+		s.span.ParentID = parent.SpanID
+	} else {
+		s.span.TraceParent = huma.NewTraceParent()
+	}
+	s.SetAttributes(attrs...)
+	return huma.WithTraceParent(ctx, s.span.TraceParent), s
+}
+
+// Spans returns the finished spans in the order they ended.
+func (r *TraceRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedSpan(nil), r.spans...)
+}
+
+// Span returns the last finished span with the name, or nil if there is none.
+func (r *TraceRecorder) Span(name string) *RecordedSpan {
+	spans := r.Spans()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+// Reset removes all recorded spans.
+func (r *TraceRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+// recordingSpan is the `huma.Span` for a `TraceRecorder`.
+type recordingSpan struct {
+	recorder *TraceRecorder
+	span     RecordedSpan
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...huma.Attribute) {
+	for _, attr := range attrs {
+		s.span.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) SetError(err error) {
+	s.span.Err = err
+}
+
+func (s *recordingSpan) End() {
+	s.span.End = time.Now()
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, s.span)
+}
@@ -0,0 +1,416 @@
+// Package idempotency provides middleware which makes unsafe operations like
+// `POST` safe to retry using the `Idempotency-Key` request header. The first
+// response for each key is stored and replayed for repeated requests, so a
+// client which retries after a network error doesn't e.g. pay twice.
+//
+//	idempotent := idempotency.New(api, idempotency.Options{})
+//
+//	huma.Register(api, huma.Operation{
+//		OperationID: "create-payment",
+//		Method:      http.MethodPost,
+//		Path:        "/payments",
+//		Middlewares: huma.Middlewares{idempotent},
+//		Metadata: map[string]any{
+//			idempotency.MetadataKey: true,
+//		},
+//	}, handler)
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// HeaderName is the request header containing the idempotency key.
+const HeaderName = "Idempotency-Key"
+
+// MetadataKey is the `huma.Operation.Metadata` key which marks operations
+// using the middleware. Set it to `true` to document the `Idempotency-Key`
+// header and 409 Conflict response for the operation.
+const MetadataKey = "idempotency"
+
+// DefaultTTL is how long responses are stored by default.
+const DefaultTTL = 24 * time.Hour
+
+// Response is a stored response which gets replayed for repeated requests.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is the stored state for an idempotency key. The response is `nil`
+// while the first request is still being handled.
+type Record struct {
+	// Fingerprint of the request body, used to detect a key being reused for
+	// a different request. It is set along with the response.
+	Fingerprint string
+
+	// Response to replay, or `nil` if the request is in flight.
+	Response *Response
+}
+
+// Store keeps track of idempotency keys. Stores must be safe for concurrent
+// use. Implement this to share keys between service instances, e.g. using
+// Redis.
+type Store interface {
+	// Start atomically records a new in-flight request for the key, unless
+	// the key already exists in which case its record is returned instead.
+	Start(ctx context.Context, key string, ttl time.Duration) (*Record, error)
+
+	// Finish stores the fingerprint and response for an in-flight request.
+	Finish(ctx context.Context, key string, record *Record, ttl time.Duration) error
+
+	// Delete removes the key so that the request can be retried.
+	Delete(ctx context.Context, key string) error
+}
+
+// Options configure idempotency keys.
+type Options struct {
+	// TTL is how long responses are stored and replayed. If unset, defaults
+	// to `DefaultTTL`.
+	TTL time.Duration
+
+	// Scope returns a value which keys are scoped to, like a tenant ID, so
+	// that different clients can use the same keys. Keys are always scoped
+	// to the request method, path, and query, and to the principal from
+	// `huma.GetPrincipal`.
+	Scope func(ctx huma.Context) string
+
+	// Store keeps track of keys. If unset, a new `MemoryStore` is used.
+	Store Store
+}
+
+// New returns a middleware which handles idempotency keys for the operations
+// it is added to with `huma.Operation.Middlewares`. Operations with
+// `MetadataKey` set to `true` get the `Idempotency-Key` header and 409
+// Conflict response documented.
+// Operation middleware runs after authentication, so a stored response is
+// only replayed to the principal which made the first request. Don't add it
+// with `huma.API.UseMiddleware`, which runs before authentication.
+//
+// Requests without a key are handled normally. Repeated requests with the
+// same key get the first response, with an `Idempotent-Replayed: true`
+// header. Requests made while the first is in flight get a 409 Conflict
+// error, and reusing a key with a different body gets a 422 Unprocessable
+// Entity error. Only successful and redirect responses are stored, so failed
+// requests can be retried.
+func New(api huma.API, opts Options) func(ctx huma.Context, next func(huma.Context)) {
+	if opts.TTL == 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+
+	middleware := func(ctx huma.Context, next func(huma.Context)) {
+		header := ctx.Header(HeaderName)
+		if header == "" {
+			next(ctx)
+			return
+		}
+
+		// Keys are scoped to the requested resource rather than the route, so
+		// e.g. a key reused for a different account isn't replayed.
+		op := ctx.Operation()
+		u := ctx.URL()
+		key := op.Method + " " + u.Path
+		if u.RawQuery != "" {
+			key += "?" + u.RawQuery
+		}
+		key += ":"
+		if opts.Scope != nil {
+			key += opts.Scope(ctx) + ":"
+		}
+		if p := huma.GetPrincipal(ctx.Context()); p != nil {
+			key += fmt.Sprint(p) + ":"
+		}
+		key += header
+
+		record, err := opts.Store.Start(ctx.Context(), key, opts.TTL)
+		if err != nil {
+			huma.WriteErr(api, ctx, http.StatusInternalServerError, "unable to check idempotency key", err)
+			return
+		}
+		if record != nil {
+			if record.Response == nil {
+				huma.WriteErr(api, ctx, http.StatusConflict, "a request with this idempotency key is in progress")
+				return
+			}
+			h := sha256.New()
+			if err := drain(ctx, io.TeeReader(bodyReader(ctx), h), op); err != nil {
+				writeBodyErr(api, ctx, err)
+				return
+			}
+			if hex.EncodeToString(h.Sum(nil)) != record.Fingerprint {
+				huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "idempotency key was already used for a different request")
+				return
+			}
+			replay(ctx, record.Response)
+			return
+		}
+
+		// The body is fingerprinted as the handler reads it, so it is not
+		// buffered and the operation's body limits still apply.
+		rc := &recordContext{
+			humaContext: ctx,
+			hash:        sha256.New(),
+			resp:        &Response{Header: http.Header{}},
+		}
+		rc.body = io.TeeReader(bodyReader(ctx), rc.hash)
+		completed := false
+		defer func() {
+			if rc.form != nil {
+				rc.form.RemoveAll()
+			}
+			if !completed {
+				// The handler panicked, so let the request be retried.
+				opts.Store.Delete(context.Background(), key)
+			}
+		}()
+		next(rc)
+		completed = true
+
+		if rc.resp.Status == 0 {
+			rc.resp.Status = http.StatusOK
+		}
+		if rc.resp.Status >= 400 {
+			// Errors may be transient, like rate limits or conflicts, so let
+			// the request be retried.
+			opts.Store.Delete(ctx.Context(), key)
+			return
+		}
+		if err := drain(ctx, rc.body, op); err != nil {
+			// The rest of the body can't be fingerprinted, so repeated
+			// requests couldn't be checked against it.
+			opts.Store.Delete(ctx.Context(), key)
+			return
+		}
+		rc.resp.Body = rc.buf.Bytes()
+		opts.Store.Finish(ctx.Context(), key, &Record{
+			Fingerprint: hex.EncodeToString(rc.hash.Sum(nil)),
+			Response:    rc.resp,
+		}, opts.TTL)
+	}
+
+	oapi := api.OpenAPI()
+	oapi.OnAddOperation = append(oapi.OnAddOperation, func(oapi *huma.OpenAPI, op *huma.Operation) {
+		if enabled, _ := op.Metadata[MetadataKey].(bool); enabled {
+			document(oapi, op)
+		}
+	})
+
+	return middleware
+}
+
+var errBodyTooLarge = errors.New("request body is too large")
+
+// bodyReader returns the request body, which may be empty.
+func bodyReader(ctx huma.Context) io.Reader {
+	if body := ctx.BodyReader(); body != nil {
+		return body
+	}
+	return bytes.NewReader(nil)
+}
+
+// drain reads the rest of the request body using the operation's read
+// timeout and body size limit, or Huma's defaults if it has no body input.
+func drain(ctx huma.Context, body io.Reader, op *huma.Operation) error {
+	timeout := op.BodyReadTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	if timeout > 0 {
+		ctx.SetReadDeadline(time.Now().Add(timeout))
+	}
+	limit := op.MaxBodyBytes
+	if limit == 0 {
+		limit = 1024 * 1024
+	}
+	if limit < 0 {
+		_, err := io.Copy(io.Discard, body)
+		return err
+	}
+	n, err := io.Copy(io.Discard, io.LimitReader(body, limit+1))
+	if err == nil && n > limit {
+		err = errBodyTooLarge
+	}
+	return err
+}
+
+// writeBodyErr writes an error for a request body which could not be read.
+func writeBodyErr(api huma.API, ctx huma.Context, err error) {
+	if err == errBodyTooLarge {
+		huma.WriteErr(api, ctx, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	huma.WriteErr(api, ctx, http.StatusBadRequest, "unable to read request body", err)
+}
+
+// replay writes a stored response.
+func replay(ctx huma.Context, resp *Response) {
+	for name, values := range resp.Header {
+		for i, value := range values {
+			if i == 0 {
+				ctx.SetHeader(name, value)
+			} else {
+				ctx.AppendHeader(name, value)
+			}
+		}
+	}
+	ctx.SetHeader("Idempotent-Replayed", "true")
+	ctx.SetStatus(resp.Status)
+	ctx.BodyWriter().Write(resp.Body)
+}
+
+// document adds the `Idempotency-Key` header parameter and the 409 Conflict
+// response to the operation.
+func document(oapi *huma.OpenAPI, op *huma.Operation) {
+	for _, p := range op.Parameters {
+		if p.In == "header" && strings.EqualFold(p.Name, HeaderName) {
+			return
+		}
+	}
+	op.Parameters = append(op.Parameters, &huma.Param{
+		Name:        HeaderName,
+		In:          "header",
+		Description: "Unique key which makes the request safe to retry. Repeated requests with the same key get the original response.",
+		Schema:      &huma.Schema{Type: huma.TypeString},
+	})
+
+	if op.Responses == nil {
+		op.Responses = map[string]*huma.Response{}
+	}
+	if op.Responses["409"] != nil {
+		return
+	}
+
+	var content map[string]*huma.MediaType
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if code >= "400" {
+			if content = op.Responses[code].Content; content != nil {
+				break
+			}
+		}
+	}
+	if content == nil && oapi.Components != nil && oapi.Components.Schemas != nil {
+		content = map[string]*huma.MediaType{
+			"application/problem+json": {
+				Schema: oapi.Components.Schemas.Schema(reflect.TypeOf(huma.ErrorModel{}), true, "Error"),
+			},
+		}
+	}
+	op.Responses["409"] = &huma.Response{
+		Description: http.StatusText(http.StatusConflict),
+		Content:     content,
+	}
+}
+
+// humaContext is embedded in the wrapper without its name conflicting with
+// the `Context()` method.
+type humaContext huma.Context
+
+// multipartMaxMemory is the maximum memory used to parse multipart forms,
+// matching the router adapters.
+const multipartMaxMemory = 8 * 1024
+
+// recordContext records the response while passing it through, and
+// fingerprints the request body as the handler reads it.
+type recordContext struct {
+	humaContext
+	body io.Reader
+	hash hash.Hash
+	form *multipart.Form
+	resp *Response
+	buf  bytes.Buffer
+}
+
+func (c *recordContext) BodyReader() io.Reader {
+	return c.body
+}
+
+// Unwrap returns the wrapped context, see `huma.GetRemoteAddr`.
+func (c *recordContext) Unwrap() huma.Context {
+	return c.humaContext
+}
+
+// GetMultipartForm parses the form from the fingerprinted body, since router
+// adapters read it directly from the request.
+func (c *recordContext) GetMultipartForm() (*multipart.Form, error) {
+	if c.form != nil {
+		return c.form, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(c.Header("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, http.ErrNotMultipart
+	}
+	if params["boundary"] == "" {
+		return nil, http.ErrMissingBoundary
+	}
+	form, err := multipart.NewReader(c.body, params["boundary"]).ReadForm(multipartMaxMemory)
+	if err != nil {
+		return nil, err
+	}
+	c.form = form
+	return form, nil
+}
+
+func (c *recordContext) SetStatus(code int) {
+	c.resp.Status = code
+	c.humaContext.SetStatus(code)
+}
+
+func (c *recordContext) SetHeader(name, value string) {
+	c.resp.Header.Set(name, value)
+	c.humaContext.SetHeader(name, value)
+}
+
+func (c *recordContext) AppendHeader(name, value string) {
+	c.resp.Header.Add(name, value)
+	c.humaContext.AppendHeader(name, value)
+}
+
+func (c *recordContext) BodyWriter() io.Writer {
+	return (*recordWriter)(c)
+}
+
+// recordWriter is the response body writer for a `recordContext`.
+type recordWriter recordContext
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	if w.resp.Status == 0 {
+		w.resp.Status = http.StatusOK
+	}
+	w.buf.Write(p)
+	return w.humaContext.BodyWriter().Write(p)
+}
+
+// Flush passes through to the underlying writer if supported.
+func (w *recordWriter) Flush() {
+	huma.Flush(w.humaContext.BodyWriter())
+}
+
+// SetWriteDeadline passes through to the underlying writer if supported.
+func (w *recordWriter) SetWriteDeadline(deadline time.Time) error {
+	return huma.SetWriteDeadline(w.humaContext.BodyWriter(), deadline)
+}
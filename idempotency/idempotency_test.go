@@ -0,0 +1,267 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+)
+
+type PaymentInput struct {
+	Body struct {
+		Amount int `json:"amount"`
+	}
+}
+
+type PaymentOutput struct {
+	Location string `header:"Location"`
+	Body     struct {
+		ID     int `json:"id"`
+		Amount int `json:"amount"`
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	_, api := humatest.New(t)
+	store := NewMemoryStore()
+	idempotent := New(api, Options{Store: store})
+
+	var mu sync.Mutex
+	count := 0
+	started := make(chan struct{})
+	release := make(chan struct{})
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		Path:          "/payments",
+		DefaultStatus: http.StatusCreated,
+		Middlewares:   huma.Middlewares{idempotent},
+		Metadata:      map[string]any{MetadataKey: true},
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		if input.Body.Amount == 999 {
+			started <- struct{}{}
+			<-release
+		}
+		if input.Body.Amount < 0 {
+			return nil, huma.Error500InternalServerError("payment failed")
+		}
+		if input.Body.Amount == 429 {
+			mu.Lock()
+			limited := count < 3
+			mu.Unlock()
+			if limited {
+				return nil, huma.NewError(http.StatusTooManyRequests, "slow down")
+			}
+		}
+		mu.Lock()
+		count++
+		resp := &PaymentOutput{}
+		resp.Body.ID = count
+		mu.Unlock()
+		resp.Location = "/payments/1"
+		resp.Body.Amount = input.Body.Amount
+		return resp, nil
+	})
+
+	// The header and conflict response are documented.
+	op := api.OpenAPI().Paths["/payments"].Post
+	if assert.NotEmpty(t, op.Parameters) {
+		assert.Equal(t, HeaderName, op.Parameters[len(op.Parameters)-1].Name)
+	}
+	assert.NotNil(t, op.Responses["409"])
+
+	resp := api.Post("/payments", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"id":1`)
+
+	// Repeated requests get the stored response.
+	resp = api.Post("/payments", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "true", resp.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/payments/1", resp.Header().Get("Location"))
+	assert.Contains(t, resp.Body.String(), `"id":1`)
+
+	// Reusing a key for a different request is an error.
+	resp = api.Post("/payments", "Idempotency-Key: a", map[string]any{"amount": 20})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	// Requests without a key are always handled.
+	resp = api.Post("/payments", map[string]any{"amount": 10})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"id":2`)
+
+	// Server errors are not stored, so the request can be retried.
+	resp = api.Post("/payments", "Idempotency-Key: b", map[string]any{"amount": -1})
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	resp = api.Post("/payments", "Idempotency-Key: b", map[string]any{"amount": -1})
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+
+	// Client errors may be transient, so they are not stored either.
+	resp = api.Post("/payments", "Idempotency-Key: d", map[string]any{"amount": 429})
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	api.Post("/payments", map[string]any{"amount": 10})
+	resp = api.Post("/payments", "Idempotency-Key: d", map[string]any{"amount": 429})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+
+	// Requests made while the first is in flight get a conflict error.
+	done := make(chan struct{})
+	go func() {
+		api.Post("/payments", "Idempotency-Key: c", map[string]any{"amount": 999})
+		close(done)
+	}()
+	<-started
+	resp = api.Post("/payments", "Idempotency-Key: c", map[string]any{"amount": 999})
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Contains(t, resp.Body.String(), "in progress")
+	close(release)
+	<-done
+
+	// Keys expire after the TTL.
+	store.now = func() time.Time { return time.Now().Add(DefaultTTL + time.Minute) }
+	resp = api.Post("/payments", "Idempotency-Key: a", map[string]any{"amount": 20})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyPrincipal(t *testing.T) {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Components.SecuritySchemes = map[string]*huma.SecurityScheme{
+		"bearer": {Type: "http", Scheme: "bearer"},
+	}
+	config.Authenticators = map[string]huma.Authenticator{
+		"bearer": func(ctx huma.Context, creds huma.Credentials) (any, error) {
+			return creds.Token, nil
+		},
+	}
+	_, api := humatest.New(t, config)
+	idempotent := New(api, Options{})
+
+	count := 0
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		Path:        "/payments",
+		Security:    []map[string][]string{{"bearer": {}}},
+		Middlewares: huma.Middlewares{idempotent},
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		count++
+		resp := &PaymentOutput{}
+		resp.Body.ID = count
+		return resp, nil
+	})
+
+	resp := api.Post("/payments", "Authorization: Bearer alice", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// Stored responses are only replayed after authentication.
+	resp = api.Post("/payments", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// Keys are scoped to the principal.
+	resp = api.Post("/payments", "Authorization: Bearer bob", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+	assert.Contains(t, resp.Body.String(), `"id":2`)
+
+	resp = api.Post("/payments", "Authorization: Bearer alice", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, "true", resp.Header().Get("Idempotent-Replayed"))
+	assert.Contains(t, resp.Body.String(), `"id":1`)
+}
+
+func TestIdempotencyPath(t *testing.T) {
+	_, api := humatest.New(t)
+	idempotent := New(api, Options{})
+
+	count := 0
+	huma.Register(api, huma.Operation{
+		Method: http.MethodPost,
+		Path:   "/accounts/{id}/pay",
+		Middlewares: huma.Middlewares{func(ctx huma.Context, next func(huma.Context)) {
+			// Wrapped middleware is still documented via the metadata.
+			idempotent(ctx, next)
+		}},
+		Metadata: map[string]any{MetadataKey: true},
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+		PaymentInput
+	}) (*PaymentOutput, error) {
+		count++
+		resp := &PaymentOutput{}
+		resp.Location = "/accounts/" + input.ID + "/payments/1"
+		resp.Body.ID = count
+		return resp, nil
+	})
+
+	op := api.OpenAPI().Paths["/accounts/{id}/pay"].Post
+	assert.NotNil(t, op.Responses["409"])
+
+	resp := api.Post("/accounts/1/pay", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "/accounts/1/payments/1", resp.Header().Get("Location"))
+
+	// Keys are scoped to the resource, not the route.
+	resp = api.Post("/accounts/2/pay", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/accounts/2/payments/1", resp.Header().Get("Location"))
+	assert.Contains(t, resp.Body.String(), `"id":2`)
+
+	resp = api.Post("/accounts/2/pay?currency=eur", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+	assert.Contains(t, resp.Body.String(), `"id":3`)
+
+	resp = api.Post("/accounts/1/pay", "Idempotency-Key: a", map[string]any{"amount": 10})
+	assert.Equal(t, "true", resp.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/accounts/1/payments/1", resp.Header().Get("Location"))
+	assert.Contains(t, resp.Body.String(), `"id":1`)
+}
+
+func TestIdempotencyMultipart(t *testing.T) {
+	_, api := humatest.New(t)
+	idempotent := New(api, Options{})
+
+	count := 0
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		Path:        "/upload",
+		Middlewares: huma.Middlewares{idempotent},
+	}, func(ctx context.Context, input *struct {
+		RawBody multipart.Form
+	}) (*PaymentOutput, error) {
+		count++
+		resp := &PaymentOutput{}
+		resp.Body.ID = count
+		resp.Body.Amount = len(input.RawBody.Value["name"])
+		return resp, nil
+	})
+
+	upload := func(name string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		w.SetBoundary("boundary")
+		w.WriteField("name", name)
+		w.Close()
+		return api.Post("/upload", "Idempotency-Key: a", "Content-Type: "+w.FormDataContentType(), body)
+	}
+
+	// The form is still parsed while the body is fingerprinted.
+	resp := upload("one")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"amount":1`)
+
+	resp = upload("one")
+	assert.Equal(t, "true", resp.Header().Get("Idempotent-Replayed"))
+	assert.Contains(t, resp.Body.String(), `"id":1`)
+
+	resp = upload("two")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+}
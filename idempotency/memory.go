@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory `Store` for a single service instance. Expired
+// keys are removed as new requests come in.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryRecord
+	swept   time.Time
+
+	// now returns the current time and can be replaced in tests.
+	now func() time.Time
+}
+
+type memoryRecord struct {
+	Record
+	expires time.Time
+}
+
+// NewMemoryStore creates a new in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: map[string]*memoryRecord{},
+		now:     time.Now,
+	}
+}
+
+// Start records a new in-flight request for the key, or returns the existing
+// record for the key.
+func (s *MemoryStore) Start(ctx context.Context, key string, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) > time.Minute {
+		for k, r := range s.records {
+			if now.After(r.expires) {
+				delete(s.records, k)
+			}
+		}
+		s.swept = now
+	}
+
+	if r := s.records[key]; r != nil && !now.After(r.expires) {
+		record := r.Record
+		return &record, nil
+	}
+	s.records[key] = &memoryRecord{expires: now.Add(ttl)}
+	return nil, nil
+}
+
+// Finish stores the fingerprint and response for the key.
+func (s *MemoryStore) Finish(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r := s.records[key]; r != nil {
+		r.Record = *record
+		r.expires = s.now().Add(ttl)
+	}
+	return nil
+}
+
+// Delete removes the key.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
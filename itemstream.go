@@ -0,0 +1,188 @@
+package huma
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2/negotiation"
+)
+
+// ItemStreamFlushInterval is the minimum time between flushes when writing an
+// `ItemStream` response. Items produced faster than this are batched into a
+// single write to the client, which is flushed at most this long after the
+// first item in the batch, while slower items are flushed as they arrive.
+var ItemStreamFlushInterval = 100 * time.Millisecond
+
+// itemStreamContentTypes are the supported content types for item streams in
+// order of preference when the client does not specify one. The JSON array
+// format is the default as it can be parsed by any JSON client.
+var itemStreamContentTypes = []string{"application/json", "application/x-ndjson", "application/jsonl"}
+
+// ItemStream is a response body which streams a collection of items to the
+// client as they are produced rather than building the whole collection in
+// memory first. It is called with a `yield` function which sends one item to
+// the client and returns `false` if the stream should stop, e.g. because the
+// client has disconnected.
+//
+//	type ListThingsOutput struct {
+//		Body huma.ItemStream[Thing]
+//	}
+//
+//	huma.Register(api, op, func(ctx context.Context, input *struct{}) (*ListThingsOutput, error) {
+//		return &ListThingsOutput{
+//			Body: func(yield func(Thing) bool) {
+//				for _, thing := range things {
+//					if !yield(thing) {
+//						return
+//					}
+//				}
+//			},
+//		}, nil
+//	})
+//
+// Clients select the format via the `Accept` header from a streamed JSON array
+// (`application/json`, the default), newline-delimited JSON
+// (`application/x-ndjson`), or JSON Lines (`application/jsonl`). Each item is
+// run through the API's transformers before being written.
+type ItemStream[T any] func(yield func(T) bool)
+
+// ItemStreamFromChan returns an `ItemStream` which sends each item received
+// from the channel until it is closed.
+func ItemStreamFromChan[T any](ch <-chan T) ItemStream[T] {
+	return func(yield func(T) bool) {
+		for item := range ch {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+func (s ItemStream[T]) itemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (s ItemStream[T]) each(yield func(any) bool) {
+	if s == nil {
+		return
+	}
+	s(func(item T) bool {
+		return yield(item)
+	})
+}
+
+// itemStreamer is implemented by all `ItemStream` types so they can be used
+// regardless of their type parameter.
+type itemStreamer interface {
+	itemType() reflect.Type
+	each(yield func(any) bool)
+}
+
+var itemStreamerType = reflect.TypeOf((*itemStreamer)(nil)).Elem()
+
+// itemStreamContent returns the documented response content for a streamed
+// collection of items with the given schema.
+func itemStreamContent(itemSchema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{
+		"application/json": {
+			Schema: &Schema{Type: TypeArray, Items: itemSchema},
+		},
+		"application/x-ndjson": {
+			Schema: itemSchema,
+		},
+		"application/jsonl": {
+			Schema: itemSchema,
+		},
+	}
+}
+
+// writeItemStream negotiates the stream format, then writes each item from the
+// stream to the client, flushing periodically. The status code is sent before
+// the first item, so errors after that point can only be reported by stopping
+// the stream.
+func writeItemStream(api API, ctx Context, status int, stream itemStreamer) {
+	ct := negotiation.SelectQValueFast(ctx.Header("Accept"), itemStreamContentTypes)
+	if ct == "" {
+		ct = itemStreamContentTypes[0]
+	}
+	array := ct == "application/json"
+
+	ctx.SetHeader("Content-Type", ct)
+	ctx.SetStatus(status)
+
+	bw := ctx.BodyWriter()
+	flusher, _ := bw.(http.Flusher)
+	statusStr := strconv.Itoa(status)
+	first := true
+
+	// Items are written from the stream's goroutine, while batched items are
+	// flushed from a timer so they aren't held back waiting for the next item.
+	var mu sync.Mutex
+	var timer *time.Timer
+	lastFlush := time.Now()
+	done := false
+	flush := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !done {
+			flusher.Flush()
+			lastFlush = time.Now()
+		}
+		timer = nil
+	}
+
+	if array {
+		bw.Write([]byte("["))
+	}
+
+	stream.each(func(item any) bool {
+		tval, terr := api.Transform(ctx, statusStr, item)
+		if terr != nil {
+			panic(fmt.Errorf("error transforming stream item %+v for %s %s %d: %w", item, ctx.Operation().Method, ctx.Operation().Path, status, terr))
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if array && !first {
+			if _, err := bw.Write([]byte(",")); err != nil {
+				// Client has likely gone away, so stop the stream.
+				return false
+			}
+		}
+		first = false
+
+		// The JSON encoder writes a trailing newline after each item, which is
+		// required for NDJSON and JSON Lines and valid whitespace in arrays.
+		if err := api.Marshal(bw, "application/json", tval); err != nil {
+			return false
+		}
+
+		if flusher != nil {
+			if wait := ItemStreamFlushInterval - time.Since(lastFlush); wait <= 0 {
+				flusher.Flush()
+				lastFlush = time.Now()
+			} else if timer == nil {
+				timer = time.AfterFunc(wait, flush)
+			}
+		}
+		return true
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	done = true
+	if timer != nil {
+		timer.Stop()
+	}
+	if array {
+		bw.Write([]byte("]"))
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,200 @@
+package huma
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// Outcomes of a request reported to a `MetricsCollector`, which tell apart
+// requests rejected by Huma, e.g. failing validation, from client errors
+// returned by the operation handler and from server errors.
+const (
+	// OutcomeOK means the request was handled successfully.
+	OutcomeOK = "ok"
+
+	// OutcomeRejected means the request got a 4xx error before the handler
+	// was called, e.g. due to authentication, content negotiation, parsing,
+	// or validation failures.
+	OutcomeRejected = "rejected"
+
+	// OutcomeClientError means the handler returned a 4xx error, e.g. for a
+	// resource which was not found.
+	OutcomeClientError = "client_error"
+
+	// OutcomeError means the response was a 5xx server error, including when
+	// the handler panicked.
+	OutcomeError = "error"
+)
+
+// RequestMetrics describes a request handled by an operation.
+type RequestMetrics struct {
+	// Method is the HTTP method of the request, which may differ from the
+	// operation's method for automatic `HEAD` operations.
+	Method string
+
+	// Status is the HTTP status code of the response.
+	Status int
+
+	// Outcome is one of `OutcomeOK`, `OutcomeRejected`, `OutcomeClientError`,
+	// or `OutcomeError`.
+	Outcome string
+
+	// Duration is how long the request took to handle, including writing the
+	// response.
+	Duration time.Duration
+
+	// RequestBytes is the number of request body bytes read.
+	RequestBytes int64
+
+	// ResponseBytes is the number of response body bytes written.
+	ResponseBytes int64
+}
+
+// MetricsCollector receives metrics for each request handled by an operation,
+// for example to expose them to Prometheus or send them to a metrics service.
+// Methods are called concurrently and should not block.
+type MetricsCollector interface {
+	// RequestStarted is called when a request for the operation starts, e.g.
+	// to track the number of requests in flight.
+	RequestStarted(op *Operation, method string)
+
+	// RequestFinished is called when the request is done, even if the
+	// handler panicked.
+	RequestFinished(op *Operation, m RequestMetrics)
+}
+
+// metricsKey is the context key for the request metrics. It is zero-size so
+// lookups don't allocate.
+type metricsKey struct{}
+
+// requestMetrics tracks a request for the `MetricsCollector`.
+type requestMetrics struct {
+	handlerCalled bool
+}
+
+// metricsHandler wraps the handler to report metrics for each request.
+func metricsHandler(collector MetricsCollector, op *Operation, handle func(Context)) func(Context) {
+	return func(ctx Context) {
+		start := time.Now()
+		method := ctx.Method()
+		collector.RequestStarted(op, method)
+
+		rm := &requestMetrics{}
+		mc := &metricsContext{humaContext: ctx}
+		completed := false
+		defer func() {
+			status := mc.status
+			if status == 0 {
+				status = http.StatusOK
+				if !completed {
+					// The handler panicked before writing a response.
+					status = http.StatusInternalServerError
+				}
+			}
+			outcome := OutcomeOK
+			switch {
+			case status >= 500:
+				outcome = OutcomeError
+			case status >= 400 && rm.handlerCalled:
+				outcome = OutcomeClientError
+			case status >= 400:
+				outcome = OutcomeRejected
+			}
+			collector.RequestFinished(op, RequestMetrics{
+				Method:        method,
+				Status:        status,
+				Outcome:       outcome,
+				Duration:      time.Since(start),
+				RequestBytes:  mc.read,
+				ResponseBytes: mc.written,
+			})
+		}()
+		handle(WithValue(mc, metricsKey{}, rm))
+		completed = true
+	}
+}
+
+// metricsFrom returns the request metrics tracker for the context, if any.
+// Skip it when metrics are disabled, as the lookup walks the whole context
+// chain.
+func metricsFrom(ctx Context) *requestMetrics {
+	rm, _ := ctx.Context().Value(metricsKey{}).(*requestMetrics)
+	return rm
+}
+
+// metricsContext counts the bytes read and written and records the status.
+type metricsContext struct {
+	humaContext
+	status  int
+	read    int64
+	written int64
+	reader  io.Reader
+}
+
+func (c *metricsContext) SetStatus(code int) {
+	c.status = code
+	c.humaContext.SetStatus(code)
+}
+
+// Unwrap returns the wrapped context, see `huma.GetRemoteAddr`.
+func (c *metricsContext) Unwrap() Context {
+	return c.humaContext
+}
+
+func (c *metricsContext) BodyReader() io.Reader {
+	if c.reader == nil {
+		r := c.humaContext.BodyReader()
+		if r == nil {
+			return nil
+		}
+		c.reader = &metricsReader{c: c, r: r}
+	}
+	return c.reader
+}
+
+func (c *metricsContext) BodyWriter() io.Writer {
+	return (*metricsWriter)(c)
+}
+
+// metricsReader counts the request body bytes read.
+type metricsReader struct {
+	c *metricsContext
+	r io.Reader
+}
+
+func (r *metricsReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.c.read += int64(n)
+	return n, err
+}
+
+// Close closes the underlying reader if supported.
+func (r *metricsReader) Close() error {
+	if closer, ok := r.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// metricsWriter is the response body writer for a `metricsContext`.
+type metricsWriter metricsContext
+
+func (w *metricsWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.humaContext.BodyWriter().Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Flush passes through to the underlying writer if supported.
+func (w *metricsWriter) Flush() {
+	Flush(w.humaContext.BodyWriter())
+}
+
+// SetWriteDeadline passes through to the underlying writer if supported.
+func (w *metricsWriter) SetWriteDeadline(deadline time.Time) error {
+	return SetWriteDeadline(w.humaContext.BodyWriter(), deadline)
+}
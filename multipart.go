@@ -0,0 +1,319 @@
+package huma
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FormFile is a file uploaded as part of a multipart form. Use it as the type
+// of a `form` tagged field in a `MultipartForm` to accept a single file, or
+// use `[]FormFile` to accept multiple files with the same name.
+type FormFile struct {
+	multipart.File
+
+	// Filename is the name of the file as sent by the client.
+	Filename string
+
+	// ContentType is the media type of the file as sent by the client,
+	// defaulting to `application/octet-stream`.
+	ContentType string
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	// IsSet is true if the file was sent by the client.
+	IsSet bool
+}
+
+// MultipartForm is a typed `multipart/form-data` request body. Use it as the
+// type of the `RawBody` input field, where `T` is a struct with `form` tagged
+// fields describing each part of the form:
+//
+//	type UploadForm struct {
+//		Avatar huma.FormFile `form:"avatar" contentType:"image/png,image/jpeg" maxSize:"1048576" required:"true"`
+//		Meta   Meta          `form:"meta"`
+//		Tags   []string      `form:"tags"`
+//	}
+//
+//	huma.Register(api, op, func(ctx context.Context, input *struct {
+//		RawBody huma.MultipartForm[UploadForm]
+//	}) (*struct{}, error) {
+//		fmt.Println(input.RawBody.Data.Avatar.Filename)
+//		return nil, nil
+//	})
+//
+// File fields use `FormFile` or `[]FormFile`. Struct and map fields are
+// decoded from JSON, while other fields are parsed from their text value.
+// All non-file fields are validated against their generated schema.
+type MultipartForm[T any] struct {
+	// Form is the parsed form, which provides access to any parts not declared
+	// in `Data`.
+	Form *multipart.Form
+
+	// Data contains the decoded form fields and files.
+	Data T
+}
+
+func (m MultipartForm[T]) multipartForm() {}
+
+// multipartFormType is implemented by all `MultipartForm` types so they can be
+// detected regardless of their type parameter.
+type multipartFormType interface {
+	multipartForm()
+}
+
+var formFileType = reflect.TypeOf(FormFile{})
+
+type formFieldInfo struct {
+	Index        int
+	Name         string
+	Required     bool
+	IsFile       bool
+	IsArray      bool
+	IsJSON       bool
+	ContentTypes []string
+	MaxSize      int64
+	Schema       *Schema
+}
+
+// findFormFields returns information about each `form` tagged field of the
+// given `MultipartForm` type, along with the generated request body media type
+// including the schema and any part encodings.
+func findFormFields(registry Registry, op *Operation, t reflect.Type) ([]*formFieldInfo, *MediaType) {
+	dataField, _ := t.FieldByName("Data")
+	dt := dataField.Type
+	if dt.Kind() != reflect.Struct {
+		panic("multipart form data must be a struct")
+	}
+
+	fields := []*formFieldInfo{}
+	s := &Schema{
+		Type:       TypeObject,
+		Properties: map[string]*Schema{},
+	}
+	mt := &MediaType{Schema: s}
+
+	for i := 0; i < dt.NumField(); i++ {
+		f := dt.Field(i)
+		name := f.Tag.Get("form")
+		if name == "" || !f.IsExported() {
+			continue
+		}
+
+		info := &formFieldInfo{
+			Index:    i,
+			Name:     name,
+			Required: boolTag(f, "required"),
+		}
+
+		if c := f.Tag.Get("contentType"); c != "" {
+			for _, ct := range strings.Split(c, ",") {
+				info.ContentTypes = append(info.ContentTypes, strings.TrimSpace(ct))
+			}
+		}
+
+		if v := f.Tag.Get("maxSize"); v != "" {
+			size, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				panic(fmt.Errorf("invalid int tag 'maxSize' for field '%s': %v (%w)", f.Name, v, err))
+			}
+			info.MaxSize = size
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Slice && ft.Elem() == formFileType {
+			info.IsArray = true
+			ft = ft.Elem()
+		}
+
+		if ft == formFileType {
+			info.IsFile = true
+			info.Schema = &Schema{
+				Type:        TypeString,
+				Format:      "binary",
+				Description: f.Tag.Get("doc"),
+			}
+			if info.IsArray {
+				info.Schema = &Schema{
+					Type:        TypeArray,
+					Description: info.Schema.Description,
+					Items:       &Schema{Type: TypeString, Format: "binary"},
+				}
+			}
+		} else {
+			if info.ContentTypes != nil || info.MaxSize != 0 {
+				panic(fmt.Errorf("contentType and maxSize tags are only supported for file fields: %s", f.Name))
+			}
+			info.IsJSON = (ft.Kind() == reflect.Struct && ft != timeType) || ft.Kind() == reflect.Map
+			info.Schema = SchemaFromField(registry, f, getHint(dt, f.Name, op.OperationID+f.Name+"Form"))
+			if info.IsJSON {
+				info.ContentTypes = []string{"application/json"}
+			}
+		}
+
+		s.Properties[name] = info.Schema
+		if info.Required {
+			s.Required = append(s.Required, name)
+		}
+
+		if len(info.ContentTypes) > 0 {
+			if mt.Encoding == nil {
+				mt.Encoding = map[string]*Encoding{}
+			}
+			mt.Encoding[name] = &Encoding{
+				ContentType: strings.Join(info.ContentTypes, ", "),
+			}
+		}
+
+		fields = append(fields, info)
+	}
+
+	return fields, mt
+}
+
+// matchContentType returns whether the media type matches any of the allowed
+// types, which may include wildcards like `image/*` or `*/*`.
+func matchContentType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if a == "*/*" || strings.EqualFold(a, mediaType) {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeMultipartForm opens, checks, and decodes each declared part of the
+// form into the data struct. Errors are reported at locations like
+// `body.avatar`. All opened files are returned so they can be closed once the
+// request is done, even if some parts failed.
+func decodeMultipartForm(r Registry, fields []*formFieldInfo, form *multipart.Form, data reflect.Value, pb *PathBuffer, res *ValidateResult, validate bool) []multipart.File {
+	opened := []multipart.File{}
+
+	for _, info := range fields {
+		pb.Push(info.Name)
+		f := data.Field(info.Index)
+
+		if info.IsFile {
+			headers := form.File[info.Name]
+			if len(headers) == 0 {
+				if validate && info.Required {
+					res.Add(pb, nil, "required file is missing")
+				}
+				pb.Pop()
+				continue
+			}
+
+			if !info.IsArray {
+				headers = headers[:1]
+			}
+
+			files := make([]FormFile, 0, len(headers))
+			for i, fh := range headers {
+				if info.IsArray {
+					pb.PushIndex(i)
+				}
+
+				contentType := fh.Header.Get("Content-Type")
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+
+				ok := true
+				if info.ContentTypes != nil && !matchContentType(contentType, info.ContentTypes) {
+					res.Add(pb, contentType, "invalid content type, expected one of "+strings.Join(info.ContentTypes, ", "))
+					ok = false
+				}
+				if info.MaxSize > 0 && fh.Size > info.MaxSize {
+					res.Add(pb, fh.Size, fmt.Sprintf("file is too large, expected at most %d bytes", info.MaxSize))
+					ok = false
+				}
+
+				if ok {
+					file, err := fh.Open()
+					if err != nil {
+						res.Add(pb, fh.Filename, "cannot open file: "+err.Error())
+					} else {
+						opened = append(opened, file)
+						files = append(files, FormFile{
+							File:        file,
+							Filename:    fh.Filename,
+							ContentType: contentType,
+							Size:        fh.Size,
+							IsSet:       true,
+						})
+					}
+				}
+
+				if info.IsArray {
+					pb.Pop()
+				}
+			}
+
+			if info.IsArray {
+				f.Set(reflect.ValueOf(files))
+			} else if len(files) > 0 {
+				f.Set(reflect.ValueOf(files[0]))
+			}
+			pb.Pop()
+			continue
+		}
+
+		values := form.Value[info.Name]
+		if len(values) == 0 {
+			if validate && info.Required {
+				res.Add(pb, nil, "required form field is missing")
+			}
+			pb.Pop()
+			continue
+		}
+
+		var value any
+		if info.IsJSON {
+			if err := json.Unmarshal([]byte(values[0]), &value); err != nil {
+				res.Add(pb, values[0], "invalid JSON: "+err.Error())
+				pb.Pop()
+				continue
+			}
+		} else {
+			count := len(res.Errors)
+			value = coerceDeepObject(r, info.Schema, pb, values, false, res)
+			if len(res.Errors) > count {
+				pb.Pop()
+				continue
+			}
+		}
+
+		if validate {
+			count := len(res.Errors)
+			Validate(r, info.Schema, pb, ModeWriteToServer, value, res)
+			if len(res.Errors) > count {
+				pb.Pop()
+				continue
+			}
+		}
+
+		// Round-trip through JSON to get the value into the field's Go type.
+		b, err := json.Marshal(value)
+		if err == nil {
+			err = json.Unmarshal(b, f.Addr().Interface())
+		}
+		if err != nil {
+			res.Add(pb, value, "invalid value: "+err.Error())
+		}
+		pb.Pop()
+	}
+
+	return opened
+}
@@ -0,0 +1,67 @@
+package huma
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// OneOfRegistry is an optional interface for a `Registry` which supports
+// polymorphic interface types. The registry created by `NewMapRegistry`
+// implements it.
+type OneOfRegistry interface {
+	RegisterOneOf(t reflect.Type, property string, variants map[string]reflect.Type)
+}
+
+// RegisterOneOf registers the concrete implementations of the interface type
+// `T` along with the name of the discriminator property used to select them.
+// Schemas generated for `T` will be a `oneOf` of the variant schemas with a
+// discriminator mapping, request bodies of type `T` are decoded into the
+// concrete type selected by the discriminator, and validation runs against
+// the selected variant only.
+//
+//	type PaymentMethod interface {
+//		isPaymentMethod()
+//	}
+//
+//	huma.RegisterOneOf[PaymentMethod](registry, "type", map[string]PaymentMethod{
+//		"card": Card{},
+//		"bank": BankAccount{},
+//	})
+//
+// The registry must implement `OneOfRegistry`, otherwise this panics.
+func RegisterOneOf[T any](registry Registry, property string, variants map[string]T) {
+	r, ok := registry.(OneOfRegistry)
+	if !ok {
+		panic(fmt.Sprintf("registry %T does not support oneOf types", registry))
+	}
+	types := make(map[string]reflect.Type, len(variants))
+	for value, variant := range variants {
+		types[value] = reflect.TypeOf(variant)
+	}
+	r.RegisterOneOf(reflect.TypeOf((*T)(nil)).Elem(), property, types)
+}
+
+// oneOfType returns the concrete Go type to decode a value into given its
+// parsed representation and the discriminated `oneOf` schema.
+func oneOfType(r Registry, s *Schema, parsed any) (reflect.Type, error) {
+	name := s.Discriminator.PropertyName
+	var value any
+	switch m := parsed.(type) {
+	case map[string]any:
+		value = m[name]
+	case map[any]any:
+		value = m[name]
+	}
+	str, _ := value.(string)
+	ref, ok := s.Discriminator.Mapping[str]
+	if !ok {
+		return nil, fmt.Errorf("unknown discriminator %s value %v", name, value)
+	}
+	if t := s.oneOfTypes[str]; t != nil {
+		return t, nil
+	}
+	if t := r.TypeFromRef(ref); t != nil {
+		return t, nil
+	}
+	return nil, fmt.Errorf("no type registered for discriminator %s value %v", name, value)
+}
@@ -857,6 +857,23 @@ type Operation struct {
 	// caution!
 	SkipValidateBody bool `yaml:"-"`
 
+	// SkipDecompressBody disables the API's `Config.RequestDecoders` for this
+	// operation, so the request body is passed through as sent along with its
+	// `Content-Encoding` header, e.g. for a `RawBody` handler which stores the
+	// compressed bytes.
+	SkipDecompressBody bool `yaml:"-"`
+
+	// StrictStatus rejects responses with a status code which is not declared
+	// by the output struct, sending a 500 Internal Server Error instead.
+	// Declare possible statuses using an `enum` tag on the output struct's
+	// `Status` field or `status` tags on body fields. The operation's default
+	// status is always allowed, while error responses are not.
+	StrictStatus bool `yaml:"-"`
+
+	// ResponseValidation overrides the API's `Config.ResponseValidation`
+	// setting for this operation. See `ResponseValidationMode` for details.
+	ResponseValidation ResponseValidationMode `yaml:"-"`
+
 	// Hidden will skip documenting this operation in the OpenAPI. This is
 	// useful for operations that are not intended to be used by clients but
 	// you'd still like the benefits of using Huma. Generally not recommended.
@@ -1362,6 +1379,35 @@ func (e *ExternalDocs) MarshalJSON() ([]byte, error) {
 	}, e.Extensions)
 }
 
+// Discriminator aids in serialization, deserialization, and validation of
+// polymorphic schemas using `oneOf` by naming the property which selects the
+// schema to use.
+//
+//	propertyName: type
+//	mapping:
+//	  card: "#/components/schemas/Card"
+//	  bank: "#/components/schemas/BankAccount"
+type Discriminator struct {
+	// PropertyName is REQUIRED. The name of the property in the payload that
+	// will hold the discriminator value.
+	PropertyName string `yaml:"propertyName"`
+
+	// Mapping holds mappings between payload values and schema names or
+	// references.
+	Mapping map[string]string `yaml:"mapping,omitempty"`
+
+	// Extensions (user-defined properties), if any. Values in this map will
+	// be marshalled as siblings of the other properties above.
+	Extensions map[string]any `yaml:",inline"`
+}
+
+func (d *Discriminator) MarshalJSON() ([]byte, error) {
+	return marshalJSON([]jsonFieldInfo{
+		{"propertyName", d.PropertyName, omitNever},
+		{"mapping", d.Mapping, omitEmpty},
+	}, d.Extensions)
+}
+
 // Tag adds metadata to a single tag that is used by the Operation Object. It is
 // not mandatory to have a Tag Object per tag defined in the Operation Object
 // instances.
@@ -1455,6 +1501,28 @@ type OpenAPI struct {
 	// `AddOperation`. You may bypass this by directly writing to the `Paths`
 	// map instead.
 	OnAddOperation []AddOpFunc `yaml:"-"`
+
+	// routes are the methods registered with the router for each path,
+	// including hidden operations and handlers added by Huma itself, like
+	// automatic `HEAD` operations and CORS preflight handlers.
+	routes map[string][]string
+}
+
+// addRoute records that the method is registered with the router for the
+// path.
+func (o *OpenAPI) addRoute(path, method string) {
+	if o.routes == nil {
+		o.routes = map[string][]string{}
+	}
+	if !slicesContains(o.routes[path], method) {
+		o.routes[path] = append(o.routes[path], method)
+	}
+}
+
+// hasRoute returns whether the method is registered with the router for the
+// path.
+func (o *OpenAPI) hasRoute(path, method string) bool {
+	return slicesContains(o.routes[path], method)
 }
 
 // AddOperation adds an operation to the OpenAPI. This is the preferred way to
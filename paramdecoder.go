@@ -0,0 +1,118 @@
+package huma
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	paramDecodersMu sync.RWMutex
+	paramDecoders   = map[reflect.Type]func(value string) (any, error){}
+)
+
+// RegisterParamDecoder registers a function which parses path, query, header,
+// and cookie parameters of type `T` from their string value. It takes
+// precedence over the built-in parsing and `encoding.TextUnmarshaler`, and is
+// also used for each item of `[]T` parameters. Decoders must be registered
+// before any operations which use the type.
+//
+//	huma.RegisterParamDecoder(func(value string) (UserID, error) {
+//		return ParseUserID(value)
+//	})
+func RegisterParamDecoder[T any](decode func(value string) (T, error)) {
+	paramDecodersMu.Lock()
+	defer paramDecodersMu.Unlock()
+	paramDecoders[reflect.TypeOf((*T)(nil)).Elem()] = func(value string) (any, error) {
+		return decode(value)
+	}
+}
+
+// UnregisterParamDecoder removes the decoder registered for type `T`, if any.
+// Operations which were already registered keep using it.
+func UnregisterParamDecoder[T any]() {
+	paramDecodersMu.Lock()
+	defer paramDecodersMu.Unlock()
+	delete(paramDecoders, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// paramDecoder returns a function to parse a parameter of the given type from
+// its string value if a decoder was registered for it or it implements
+// `encoding.TextUnmarshaler`, otherwise `nil`. This takes precedence over the
+// built-in parsing, so e.g. string enums can validate their values. Times are
+// excluded as they are parsed using the `timeFormat` tag instead.
+func paramDecoder(t reflect.Type) func(value string) (any, error) {
+	paramDecodersMu.RLock()
+	decode := paramDecoders[t]
+	paramDecodersMu.RUnlock()
+	if decode != nil {
+		return decode
+	}
+
+	if t != timeType && reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return func(value string) (any, error) {
+			v := reflect.New(t)
+			err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+			return v.Elem().Interface(), err
+		}
+	}
+	return nil
+}
+
+// decodeParam parses the parameter value into the field using its decoder,
+// splitting it into items first for slices of decodable types. Returns the
+// value to validate against the parameter's schema and whether parsing
+// succeeded.
+func decodeParam(p *paramFieldInfo, f reflect.Value, value string, exploded []string, delimiter string, pb *PathBuffer, res *ValidateResult) (any, bool) {
+	if !p.DecodeItems {
+		v, err := p.Decode(value)
+		if err != nil {
+			res.Add(pb, value, "invalid value: "+err.Error())
+			return nil, false
+		}
+		f.Set(reflect.ValueOf(v))
+		return paramValidationValue(p.Schema, value, f), true
+	}
+
+	values := exploded
+	if values == nil {
+		values = strings.Split(value, delimiter)
+	}
+	items := reflect.MakeSlice(f.Type(), 0, len(values))
+	for _, item := range values {
+		v, err := p.Decode(item)
+		if err != nil {
+			res.Add(pb, item, "invalid value: "+err.Error())
+			return nil, false
+		}
+		items = reflect.Append(items, reflect.ValueOf(v))
+	}
+	f.Set(items)
+
+	itemSchema := p.Schema
+	if itemSchema != nil && itemSchema.Items != nil {
+		itemSchema = itemSchema.Items
+	}
+	return paramValidationValue(itemSchema, values, f), true
+}
+
+// paramValidationValue returns the value to validate for a decoded parameter.
+// String schemas describe the text sent by the client, so the raw value is
+// used. Otherwise the decoded value is converted into its generic JSON
+// representation, e.g. a number for an `int` based type.
+func paramValidationValue(s *Schema, raw any, f reflect.Value) any {
+	if s == nil || s.Type == TypeString {
+		return raw
+	}
+	b, err := json.Marshal(f.Interface())
+	if err != nil {
+		return raw
+	}
+	var parsed any
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		return raw
+	}
+	return parsed
+}
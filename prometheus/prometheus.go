@@ -0,0 +1,239 @@
+// Package prometheus provides a `huma.MetricsCollector` which keeps request
+// metrics for each operation in memory and serves them in the Prometheus text
+// exposition format, without depending on the Prometheus client library.
+//
+//	collector := prometheus.New(prometheus.Options{})
+//	config := huma.DefaultConfig("My API", "1.0.0")
+//	config.Metrics = collector
+//	api := humachi.New(router, config)
+//	collector.Register(api, "/metrics")
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// DefaultDurationBuckets are the default request duration histogram buckets
+// in seconds.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the default request and response body size
+// histogram buckets in bytes.
+var DefaultSizeBuckets = []float64{100, 1000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// Options configure the collector.
+type Options struct {
+	// Namespace is the prefix for metric names. If unset, defaults to `huma`,
+	// e.g. `huma_requests_total`.
+	Namespace string
+
+	// DurationBuckets are the request duration histogram buckets in seconds.
+	// If unset, defaults to `DefaultDurationBuckets`.
+	DurationBuckets []float64
+
+	// SizeBuckets are the body size histogram buckets in bytes. If unset,
+	// defaults to `DefaultSizeBuckets`.
+	SizeBuckets []float64
+}
+
+// Collector keeps metrics for each operation. It provides the following
+// metrics, labelled by `operation` and `method`:
+//
+//   - `requests_total` counter, also labelled by `status` and `outcome`
+//   - `request_duration_seconds` histogram, also labelled by `status`
+//   - `request_size_bytes` and `response_size_bytes` histograms
+//   - `requests_in_flight` gauge
+type Collector struct {
+	opts Options
+
+	mu        sync.Mutex
+	requests  map[string]float64
+	durations map[string]*histogram
+	reqSizes  map[string]*histogram
+	respSizes map[string]*histogram
+	inFlight  map[string]float64
+}
+
+var _ huma.MetricsCollector = (*Collector)(nil)
+
+// New creates a new collector.
+func New(opts Options) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = "huma"
+	}
+	if opts.DurationBuckets == nil {
+		opts.DurationBuckets = DefaultDurationBuckets
+	}
+	if opts.SizeBuckets == nil {
+		opts.SizeBuckets = DefaultSizeBuckets
+	}
+	return &Collector{
+		opts:      opts,
+		requests:  map[string]float64{},
+		durations: map[string]*histogram{},
+		reqSizes:  map[string]*histogram{},
+		respSizes: map[string]*histogram{},
+		inFlight:  map[string]float64{},
+	}
+}
+
+// operationName returns the operation label value, falling back to the
+// method and path for operations without an ID.
+func operationName(op *huma.Operation) string {
+	if op.OperationID != "" {
+		return op.OperationID
+	}
+	return op.Method + " " + op.Path
+}
+
+// labelEscaper escapes label values for the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels formats label pairs, escaping the values.
+func labels(pairs ...string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(pairs[i])
+		sb.WriteString(`="`)
+		sb.WriteString(labelEscaper.Replace(pairs[i+1]))
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
+
+// RequestStarted tracks a request in flight.
+func (c *Collector) RequestStarted(op *huma.Operation, method string) {
+	key := labels("method", method, "operation", operationName(op))
+	c.mu.Lock()
+	c.inFlight[key]++
+	c.mu.Unlock()
+}
+
+// RequestFinished records the metrics for a finished request.
+func (c *Collector) RequestFinished(op *huma.Operation, m huma.RequestMetrics) {
+	name := operationName(op)
+	status := strconv.Itoa(m.Status)
+	base := labels("method", m.Method, "operation", name)
+	withStatus := labels("method", m.Method, "operation", name, "status", status)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight[base]--
+	c.requests[labels("method", m.Method, "operation", name, "outcome", m.Outcome, "status", status)]++
+	c.observe(c.durations, withStatus, c.opts.DurationBuckets, m.Duration.Seconds())
+	c.observe(c.reqSizes, base, c.opts.SizeBuckets, float64(m.RequestBytes))
+	c.observe(c.respSizes, base, c.opts.SizeBuckets, float64(m.ResponseBytes))
+}
+
+// observe adds a value to the histogram for the labels.
+func (c *Collector) observe(m map[string]*histogram, key string, buckets []float64, v float64) {
+	h := m[key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		m[key] = h
+	}
+	for i, le := range buckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// histogram holds cumulative bucket counts.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	ns := c.opts.Namespace
+
+	c.mu.Lock()
+	writeValues(buf, ns+"_requests_total", "counter", "Total number of requests handled by operations.", c.requests)
+	writeHistograms(buf, ns+"_request_duration_seconds", "Request duration in seconds.", c.durations, c.opts.DurationBuckets)
+	writeHistograms(buf, ns+"_request_size_bytes", "Request body size in bytes.", c.reqSizes, c.opts.SizeBuckets)
+	writeHistograms(buf, ns+"_response_size_bytes", "Response body size in bytes.", c.respSizes, c.opts.SizeBuckets)
+	writeValues(buf, ns+"_requests_in_flight", "gauge", "Number of requests currently being handled.", c.inFlight)
+	c.mu.Unlock()
+
+	return buf.WriteTo(w)
+}
+
+// Handle serves the metrics for a request.
+func (c *Collector) Handle(ctx huma.Context) {
+	ctx.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	ctx.SetStatus(http.StatusOK)
+	c.WriteTo(ctx.BodyWriter())
+}
+
+// Register serves the metrics from the path using the API's router. The
+// endpoint is not documented in the OpenAPI and its requests are not
+// included in the metrics.
+func (c *Collector) Register(api huma.API, path string) {
+	api.Adapter().Handle(&huma.Operation{
+		Method: http.MethodGet,
+		Path:   path,
+	}, c.Handle)
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeValues(buf *bytes.Buffer, name, typ, help string, values map[string]float64) {
+	if len(values) == 0 {
+		return
+	}
+	writeHeader(buf, name, typ, help)
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(buf, "%s{%s} %s\n", name, key, formatFloat(values[key]))
+	}
+}
+
+func writeHistograms(buf *bytes.Buffer, name, help string, histograms map[string]*histogram, buckets []float64) {
+	if len(histograms) == 0 {
+		return
+	}
+	writeHeader(buf, name, "histogram", help)
+	for _, key := range sortedKeys(histograms) {
+		h := histograms[key]
+		for i, le := range buckets {
+			fmt.Fprintf(buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, key, formatFloat(le), h.counts[i])
+		}
+		fmt.Fprintf(buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, key, h.count)
+		fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, key, formatFloat(h.sum))
+		fmt.Fprintf(buf, "%s_count{%s} %d\n", name, key, h.count)
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
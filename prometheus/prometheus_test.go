@@ -0,0 +1,65 @@
+package prometheus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/danielgtaylor/huma/v2/prometheus"
+)
+
+func TestPrometheus(t *testing.T) {
+	collector := prometheus.New(prometheus.Options{})
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Metrics = collector
+	_, api := humatest.New(t, config)
+	collector.Register(api, "/metrics")
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id" maxLength:"5"`
+	}) (*struct{ Body string }, error) {
+		switch input.ID {
+		case "fail":
+			return nil, huma.Error404NotFound("thing not found")
+		case "error":
+			return nil, errors.New("database unavailable")
+		case "panic":
+			panic("oops")
+		}
+		return &struct{ Body string }{Body: "hello"}, nil
+	})
+
+	api.Get("/things/foo")
+	api.Get("/things/toolong")
+	api.Get("/things/fail")
+	api.Get("/things/error")
+	assert.Panics(t, func() {
+		api.Get("/things/panic")
+	})
+
+	resp := api.Get("/metrics")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "text/plain")
+
+	body := resp.Body.String()
+	assert.Contains(t, body, "# TYPE huma_requests_total counter\n")
+	assert.Contains(t, body, `huma_requests_total{method="GET",operation="get-thing",outcome="ok",status="200"} 1`)
+	assert.Contains(t, body, `huma_requests_total{method="GET",operation="get-thing",outcome="rejected",status="422"} 1`)
+	assert.Contains(t, body, `huma_requests_total{method="GET",operation="get-thing",outcome="client_error",status="404"} 1`)
+	assert.Contains(t, body, `huma_requests_total{method="GET",operation="get-thing",outcome="error",status="500"} 2`)
+	assert.Contains(t, body, `huma_request_duration_seconds_count{method="GET",operation="get-thing",status="200"} 1`)
+	assert.Contains(t, body, `huma_response_size_bytes_bucket{method="GET",operation="get-thing",le="100"} 2`)
+	assert.Contains(t, body, `huma_requests_in_flight{method="GET",operation="get-thing"} 0`)
+
+	// The metrics endpoint itself is not measured.
+	assert.NotContains(t, body, "/metrics")
+}
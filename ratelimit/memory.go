@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory `Store` for a single service instance. Expired
+// entries are removed as new requests come in.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	swept   time.Time
+
+	// now returns the current time and can be replaced in tests.
+	now func() time.Time
+}
+
+// entry holds the state for a key. Token buckets use `tokens` and `last`,
+// while sliding windows use `start`, `prev`, and `curr`.
+type entry struct {
+	tokens  float64
+	last    time.Time
+	start   time.Time
+	prev    int
+	curr    int
+	expires time.Time
+}
+
+// NewMemoryStore creates a new in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: map[string]*entry{},
+		now:     time.Now,
+	}
+}
+
+// Take counts a request for the key against the policy.
+func (s *MemoryStore) Take(ctx context.Context, key string, policy *Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) > time.Minute {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.swept = now
+	}
+
+	e := s.entries[key]
+	if e == nil {
+		e = &entry{tokens: float64(policy.Limit), last: now, start: now}
+		s.entries[key] = e
+	}
+
+	var res Result
+	if policy.Algorithm == SlidingWindow {
+		res = e.slidingWindow(now, policy)
+	} else {
+		res = e.tokenBucket(now, policy)
+	}
+	e.expires = now.Add(2 * policy.Window)
+	return res, nil
+}
+
+// tokenBucket refills the bucket for the time since the last request, then
+// takes a token if one is available.
+func (e *entry) tokenBucket(now time.Time, policy *Policy) Result {
+	limit := float64(policy.Limit)
+	rate := limit / float64(policy.Window)
+
+	e.tokens = math.Min(limit, e.tokens+float64(now.Sub(e.last))*rate)
+	e.last = now
+
+	res := Result{Limit: policy.Limit}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1 - e.tokens) / rate))
+	}
+	res.Remaining = int(e.tokens)
+	res.Reset = time.Duration(math.Ceil((limit - e.tokens) / rate))
+	return res
+}
+
+// slidingWindow estimates the requests in the last window from the counts of
+// the current and previous fixed windows, then counts the request if it is
+// within the limit.
+func (e *entry) slidingWindow(now time.Time, policy *Policy) Result {
+	window := policy.Window
+	if elapsed := now.Sub(e.start); elapsed >= window {
+		// Move to the window containing `now`.
+		windows := elapsed / window
+		if windows == 1 {
+			e.prev = e.curr
+		} else {
+			e.prev = 0
+		}
+		e.curr = 0
+		e.start = e.start.Add(windows * window)
+	}
+
+	elapsed := now.Sub(e.start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimate := float64(e.prev)*weight + float64(e.curr)
+
+	res := Result{Limit: policy.Limit, Reset: window - elapsed}
+	if estimate+1 <= float64(policy.Limit) {
+		e.curr++
+		estimate++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = window - elapsed
+		if e.prev > 0 && e.curr < policy.Limit {
+			// Wait until enough of the previous window has slid out.
+			needed := 1 - float64(policy.Limit-e.curr-1)/float64(e.prev)
+			if wait := time.Duration(needed*float64(window)) - elapsed; wait < res.RetryAfter {
+				res.RetryAfter = wait
+			}
+		}
+	}
+	res.Remaining = int(math.Max(0, float64(policy.Limit)-estimate))
+	return res
+}
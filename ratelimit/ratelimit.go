@@ -0,0 +1,347 @@
+// Package ratelimit provides middleware to throttle requests per client and
+// per operation. Limited requests get a 429 Too Many Requests error with a
+// `Retry-After` header, and all limited responses include the IETF
+// `RateLimit-Limit`, `RateLimit-Remaining`, and `RateLimit-Reset` headers.
+//
+//	api.UseMiddleware(ratelimit.New(api, ratelimit.Options{
+//		Policy: &ratelimit.Policy{Limit: 100, Window: time.Minute},
+//	}))
+//
+// Operations can set their own policy using the operation metadata:
+//
+//	huma.Register(api, huma.Operation{
+//		OperationID: "create-order",
+//		Method:      http.MethodPost,
+//		Path:        "/orders",
+//		Metadata: map[string]any{
+//			ratelimit.MetadataKey: &ratelimit.Policy{Limit: 10, Window: time.Minute},
+//		},
+//	}, handler)
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// MetadataKey is the `huma.Operation.Metadata` key for an operation's
+// `*Policy`, which replaces the default policy for that operation.
+const MetadataKey = "ratelimit"
+
+// Algorithm is a rate limiting algorithm.
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts of up to the limit, then refills the bucket
+	// evenly over the window.
+	TokenBucket Algorithm = iota
+
+	// SlidingWindow allows up to the limit in any window, weighting the
+	// previous window's count by how much of it overlaps the current one.
+	SlidingWindow
+)
+
+// KeyFunc returns the key identifying a client, like its IP address or user
+// ID. Requests with the same key share a limit, including requests with an
+// empty key.
+type KeyFunc func(ctx huma.Context) string
+
+// ByIP identifies clients by the IP address of the connection, see
+// `huma.GetRemoteAddr`. Behind a reverse proxy or load balancer all requests
+// come from the proxy, so use `ByForwardedIP` instead.
+func ByIP(ctx huma.Context) string {
+	return remoteIP(ctx)
+}
+
+// ByForwardedIP returns a key function which identifies clients by the IP
+// address of the connection, unless it is one of the trusted proxies given as
+// IP addresses or CIDR ranges like `10.0.0.0/8`. Requests from a trusted
+// proxy are identified by the `X-Real-IP` header, or by the last address in
+// the `X-Forwarded-For` header which is not a trusted proxy. Headers from
+// other clients are ignored, as clients can send them too. It panics if a
+// proxy is not a valid address or range.
+func ByForwardedIP(trustedProxies ...string) KeyFunc {
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		var prefix netip.Prefix
+		var err error
+		if strings.Contains(proxy, "/") {
+			prefix, err = netip.ParsePrefix(proxy)
+		} else {
+			var addr netip.Addr
+			addr, err = netip.ParseAddr(proxy)
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if err != nil {
+			panic(fmt.Errorf("invalid trusted proxy %q: %w", proxy, err))
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	trusted := func(ip string) bool {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(ctx huma.Context) string {
+		ip := remoteIP(ctx)
+		if !trusted(ip) {
+			return ip
+		}
+		if realIP := strings.TrimSpace(ctx.Header("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+		forwarded := strings.Split(ctx.Header("X-Forwarded-For"), ",")
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			addr := strings.TrimSpace(forwarded[i])
+			if addr == "" {
+				continue
+			}
+			ip = addr
+			if !trusted(ip) {
+				break
+			}
+		}
+		return ip
+	}
+}
+
+// remoteIP returns the IP address of the connection, without the port.
+func remoteIP(ctx huma.Context) string {
+	addr := huma.GetRemoteAddr(ctx)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// ByPrincipal identifies clients by the authenticated principal from
+// `huma.GetPrincipal`, which should be a string or implement `fmt.Stringer`.
+// Since authentication runs after API middleware, use this with
+// `huma.Operation.Middlewares` rather than `huma.API.UseMiddleware`.
+func ByPrincipal(ctx huma.Context) string {
+	p := huma.GetPrincipal(ctx.Context())
+	if p == nil {
+		return ""
+	}
+	return fmt.Sprint(p)
+}
+
+// ByHeader returns a key function which identifies clients by the value of a
+// request header, like an API key.
+func ByHeader(name string) KeyFunc {
+	return func(ctx huma.Context) string {
+		return ctx.Header(name)
+	}
+}
+
+// Policy describes how many requests a client may make.
+type Policy struct {
+	// Name of the policy. Operations with policies of the same name share
+	// limits. If unset, operation policies are per operation and the default
+	// policy is shared by all operations.
+	Name string
+
+	// Limit is the number of requests allowed per window. A limit of zero
+	// disables rate limiting.
+	Limit int
+
+	// Window is the period of time the limit applies to.
+	Window time.Duration
+
+	// Algorithm used to limit requests, which defaults to `TokenBucket`.
+	Algorithm Algorithm
+
+	// Key identifies clients for this policy. If unset, the key function
+	// from the options is used.
+	Key KeyFunc
+}
+
+// Result is the outcome of taking a request from a limit.
+type Result struct {
+	// Allowed is true if the request is within the limit.
+	Allowed bool
+
+	// Limit is the number of requests allowed per window.
+	Limit int
+
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+
+	// Reset is the time until the limit is fully available again.
+	Reset time.Duration
+
+	// RetryAfter is the time until a denied request may be retried.
+	RetryAfter time.Duration
+}
+
+// Store keeps track of the requests made for each key. Stores must support
+// both algorithms and be safe for concurrent use. Implement this to share
+// limits between service instances, e.g. using Redis.
+type Store interface {
+	// Take counts a request for the key against the policy.
+	Take(ctx context.Context, key string, policy *Policy) (Result, error)
+}
+
+// Options configure rate limiting.
+type Options struct {
+	// Policy is the default policy for operations which don't set one in
+	// their metadata. If unset, only operations with a policy are limited.
+	Policy *Policy
+
+	// Key identifies clients. If unset, defaults to `ByIP`, which identifies
+	// clients by the connection's address. Use `ByForwardedIP` behind a
+	// reverse proxy.
+	Key KeyFunc
+
+	// Store keeps track of requests. If unset, a new `MemoryStore` is used.
+	Store Store
+}
+
+// New returns a middleware which limits requests based on the operation's
+// policy, and documents the 429 Too Many Requests response for limited
+// operations registered after it is created. Store errors allow the request
+// rather than failing it.
+func New(api huma.API, opts Options) func(ctx huma.Context, next func(huma.Context)) {
+	if opts.Key == nil {
+		opts.Key = ByIP
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+
+	oapi := api.OpenAPI()
+	oapi.OnAddOperation = append(oapi.OnAddOperation, func(oapi *huma.OpenAPI, op *huma.Operation) {
+		if policyFor(op, opts.Policy) != nil {
+			document(oapi, op)
+		}
+	})
+
+	return func(ctx huma.Context, next func(huma.Context)) {
+		op := ctx.Operation()
+		policy := policyFor(op, opts.Policy)
+		if policy == nil {
+			next(ctx)
+			return
+		}
+
+		keyFunc := policy.Key
+		if keyFunc == nil {
+			keyFunc = opts.Key
+		}
+		name := policy.Name
+		if name == "" {
+			name = "default"
+			if policy != opts.Policy {
+				name = op.Method + " " + op.Path
+			}
+		}
+
+		res, err := opts.Store.Take(ctx.Context(), name+":"+keyFunc(ctx), policy)
+		if err != nil {
+			next(ctx)
+			return
+		}
+
+		ctx.SetHeader("RateLimit-Limit", strconv.Itoa(res.Limit))
+		ctx.SetHeader("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		ctx.SetHeader("RateLimit-Reset", seconds(res.Reset))
+		if !res.Allowed {
+			retry := seconds(res.RetryAfter)
+			ctx.SetHeader("Retry-After", retry)
+			huma.WriteErr(api, ctx, http.StatusTooManyRequests, "rate limit exceeded, retry in "+retry+" seconds")
+			return
+		}
+		next(ctx)
+	}
+}
+
+// policyFor returns the policy for the operation, or nil if it is not
+// limited.
+func policyFor(op *huma.Operation, fallback *Policy) *Policy {
+	policy := fallback
+	if op != nil {
+		if p, ok := op.Metadata[MetadataKey].(*Policy); ok {
+			policy = p
+		}
+	}
+	if policy == nil || policy.Limit <= 0 || policy.Window <= 0 {
+		return nil
+	}
+	return policy
+}
+
+// seconds formats the duration as a whole number of seconds, rounding up so
+// clients don't retry too early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// document adds the 429 Too Many Requests response to the operation, using
+// the same content as its other error responses.
+func document(oapi *huma.OpenAPI, op *huma.Operation) {
+	if op.Responses == nil {
+		op.Responses = map[string]*huma.Response{}
+	}
+	if op.Responses["429"] != nil {
+		return
+	}
+
+	var content map[string]*huma.MediaType
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if code >= "400" || code == "default" {
+			content = op.Responses[code].Content
+			if content != nil {
+				break
+			}
+		}
+	}
+	if content == nil && oapi.Components != nil && oapi.Components.Schemas != nil {
+		content = map[string]*huma.MediaType{
+			"application/problem+json": {
+				Schema: oapi.Components.Schemas.Schema(reflect.TypeOf(huma.ErrorModel{}), true, "Error"),
+			},
+		}
+	}
+
+	intHeader := func(desc string) *huma.Header {
+		return &huma.Header{
+			Description: desc,
+			Schema:      &huma.Schema{Type: huma.TypeInteger},
+		}
+	}
+	op.Responses["429"] = &huma.Response{
+		Description: http.StatusText(http.StatusTooManyRequests),
+		Headers: map[string]*huma.Header{
+			"Retry-After":         intHeader("Seconds until the request may be retried."),
+			"RateLimit-Limit":     intHeader("Requests allowed per window."),
+			"RateLimit-Remaining": intHeader("Requests remaining in the current window."),
+			"RateLimit-Reset":     intHeader("Seconds until the limit resets."),
+		},
+		Content: content,
+	}
+}
@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+)
+
+func TestRateLimit(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(New(api, Options{
+		Policy: &Policy{Limit: 2, Window: time.Minute},
+		Key:    ByForwardedIP("127.0.0.1"),
+	}))
+
+	huma.Get(api, "/default", func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+	huma.Register(api, huma.Operation{
+		Method: http.MethodPost,
+		Path:   "/orders",
+		Metadata: map[string]any{
+			MetadataKey: &Policy{Limit: 1, Window: time.Minute, Key: ByHeader("X-API-Key")},
+		},
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/unlimited",
+		Metadata: map[string]any{
+			MetadataKey: &Policy{},
+		},
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	// Limited operations document the 429 response.
+	resp429 := api.OpenAPI().Paths["/orders"].Post.Responses["429"]
+	if assert.NotNil(t, resp429) {
+		assert.NotNil(t, resp429.Headers["Retry-After"])
+		assert.NotNil(t, resp429.Content)
+	}
+	assert.Nil(t, api.OpenAPI().Paths["/unlimited"].Get.Responses["429"])
+
+	resp := api.Get("/default", "X-Real-IP: 1.2.3.4")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", resp.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", resp.Header().Get("RateLimit-Reset"))
+
+	resp = api.Get("/default", "X-Real-IP: 1.2.3.4")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+
+	resp = api.Get("/default", "X-Real-IP: 1.2.3.4")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+	assert.Contains(t, resp.Body.String(), "rate limit exceeded")
+
+	// Other clients have their own limit.
+	resp = api.Get("/default", "X-Forwarded-For: 9.9.9.9, 5.6.7.8")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	// Operation policies are separate from the default policy.
+	resp = api.Post("/orders", "X-API-Key: abc")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	resp = api.Post("/orders", "X-API-Key: abc")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	resp = api.Post("/orders", "X-API-Key: def")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	// A zero limit disables rate limiting.
+	for i := 0; i < 3; i++ {
+		resp = api.Get("/unlimited", "X-Real-IP: 1.2.3.4")
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+		assert.Empty(t, resp.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	_, api := humatest.New(t)
+	keys := map[string]KeyFunc{
+		"ip":          ByIP,
+		"proxy":       ByForwardedIP("127.0.0.0/8", "10.0.0.1"),
+		"other-proxy": ByForwardedIP("192.168.0.1"),
+	}
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		for name, key := range keys {
+			ctx.SetHeader("X-Key-"+name, key(ctx))
+		}
+		next(ctx)
+	})
+	huma.Get(api, "/", func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	// Requests from the test client come from 127.0.0.1.
+	resp := api.Get("/")
+	assert.Equal(t, "127.0.0.1", resp.Header().Get("X-Key-ip"))
+	assert.Equal(t, "127.0.0.1", resp.Header().Get("X-Key-proxy"))
+
+	// Headers are only trusted from proxies.
+	resp = api.Get("/", "X-Real-IP: 1.2.3.4")
+	assert.Equal(t, "127.0.0.1", resp.Header().Get("X-Key-ip"))
+	assert.Equal(t, "1.2.3.4", resp.Header().Get("X-Key-proxy"))
+	assert.Equal(t, "127.0.0.1", resp.Header().Get("X-Key-other-proxy"))
+
+	// The last address which isn't a trusted proxy is the client.
+	resp = api.Get("/", "X-Forwarded-For: 9.9.9.9, 5.6.7.8, 10.0.0.1")
+	assert.Equal(t, "5.6.7.8", resp.Header().Get("X-Key-proxy"))
+
+	assert.Panics(t, func() {
+		ByForwardedIP("not-an-ip")
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := &Policy{Limit: 10, Window: 10 * time.Second}
+
+	// The full limit can be used in a burst.
+	for i := 0; i < 10; i++ {
+		res, _ := store.Take(context.Background(), "key", policy)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 9-i, res.Remaining)
+	}
+	res, _ := store.Take(context.Background(), "key", policy)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 10*time.Second, res.Reset)
+
+	// Tokens refill evenly over the window.
+	now = now.Add(2 * time.Second)
+	res, _ = store.Take(context.Background(), "key", policy)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := &Policy{Limit: 4, Window: 10 * time.Second, Algorithm: SlidingWindow}
+
+	for i := 0; i < 4; i++ {
+		res, _ := store.Take(context.Background(), "key", policy)
+		assert.True(t, res.Allowed)
+	}
+	res, _ := store.Take(context.Background(), "key", policy)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 10*time.Second, res.RetryAfter)
+
+	// Halfway through the next window, half of the previous requests still
+	// count against the limit.
+	now = now.Add(15 * time.Second)
+	res, _ = store.Take(context.Background(), "key", policy)
+	assert.True(t, res.Allowed)
+	res, _ = store.Take(context.Background(), "key", policy)
+	assert.True(t, res.Allowed)
+	res, _ = store.Take(context.Background(), "key", policy)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 5*time.Second, res.Reset)
+	assert.Equal(t, 2500*time.Millisecond, res.RetryAfter)
+
+	// Requests from long ago no longer count.
+	now = now.Add(time.Minute)
+	res, _ = store.Take(context.Background(), "key", policy)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 3, res.Remaining)
+}
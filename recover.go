@@ -0,0 +1,152 @@
+package huma
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError describes a panic recovered while handling a request for an
+// operation, see `Config.RecoverPanics`.
+type PanicError struct {
+	// Operation is the operation which was handling the request.
+	Operation *Operation
+
+	// Value is the value passed to `panic`.
+	Value any
+
+	// Stack is the stack trace of the goroutine which panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverHandler wraps the handler to recover from panics, writing a 500
+// Internal Server Error response unless the response was already started.
+// Headers set by the handler are held back until the response starts, so
+// that the error response doesn't include e.g. the `ETag` or `Location` of
+// the successful response.
+func recoverHandler(api API, op *Operation, handle func(Context)) func(Context) {
+	onPanic := configOf(api).OnPanic
+	return func(ctx Context) {
+		rc := &recoverContext{humaContext: ctx}
+		defer func() {
+			v := recover()
+			if v == nil {
+				// Send headers even if no status or body was written.
+				rc.start()
+				return
+			}
+			if v == http.ErrAbortHandler {
+				// The server uses this to abort the response without logging.
+				panic(v)
+			}
+
+			err := &PanicError{Operation: op, Value: v, Stack: debug.Stack()}
+			traceFrom(ctx).fail(err)
+			if onPanic != nil {
+				onPanic(ctx, err)
+			}
+			if !rc.started {
+				func() {
+					// The error response runs transformers, which may panic again.
+					// The original panic was already reported, so ignore it.
+					defer func() { recover() }()
+					WriteErr(api, ctx, http.StatusInternalServerError, "internal server error")
+				}()
+			}
+		}()
+		handle(rc)
+	}
+}
+
+// recoverContext tracks whether the response was started, after which an
+// error response can no longer be written, and holds back headers until then.
+type recoverContext struct {
+	humaContext
+	started bool
+	headers []pendingHeader
+}
+
+// pendingHeader is a header set before the response was started.
+type pendingHeader struct {
+	name, value string
+	append      bool
+}
+
+// start marks the response as started and sends the pending headers.
+func (c *recoverContext) start() {
+	if c.started {
+		return
+	}
+	c.started = true
+	for _, h := range c.headers {
+		if h.append {
+			c.humaContext.AppendHeader(h.name, h.value)
+		} else {
+			c.humaContext.SetHeader(h.name, h.value)
+		}
+	}
+	c.headers = nil
+}
+
+// Unwrap returns the wrapped context, see `huma.GetRemoteAddr`.
+func (c *recoverContext) Unwrap() Context {
+	return c.humaContext
+}
+
+func (c *recoverContext) SetHeader(name, value string) {
+	if c.started {
+		c.humaContext.SetHeader(name, value)
+		return
+	}
+	c.headers = append(c.headers, pendingHeader{name: name, value: value})
+}
+
+func (c *recoverContext) AppendHeader(name, value string) {
+	if c.started {
+		c.humaContext.AppendHeader(name, value)
+		return
+	}
+	c.headers = append(c.headers, pendingHeader{name: name, value: value, append: true})
+}
+
+func (c *recoverContext) SetStatus(code int) {
+	c.start()
+	c.humaContext.SetStatus(code)
+}
+
+func (c *recoverContext) BodyWriter() io.Writer {
+	return (*recoverWriter)(c)
+}
+
+// recoverWriter is the response body writer for a `recoverContext`.
+type recoverWriter recoverContext
+
+func (w *recoverWriter) Write(p []byte) (int, error) {
+	(*recoverContext)(w).start()
+	return w.humaContext.BodyWriter().Write(p)
+}
+
+// Flush passes through to the underlying writer if supported.
+func (w *recoverWriter) Flush() {
+	(*recoverContext)(w).start()
+	Flush(w.humaContext.BodyWriter())
+}
+
+// SetWriteDeadline passes through to the underlying writer if supported.
+func (w *recoverWriter) SetWriteDeadline(deadline time.Time) error {
+	return SetWriteDeadline(w.humaContext.BodyWriter(), deadline)
+}
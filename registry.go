@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"unicode/utf8"
 )
@@ -65,6 +66,13 @@ type mapRegistry struct {
 	seen    map[reflect.Type]bool
 	namer   func(reflect.Type, string) string
 	aliases map[reflect.Type]reflect.Type
+	oneOfs  map[reflect.Type]*oneOfInfo
+}
+
+// oneOfInfo describes a registered polymorphic interface type.
+type oneOfInfo struct {
+	property string
+	variants map[string]reflect.Type
 }
 
 func (r *mapRegistry) Schema(t reflect.Type, allowRef bool, hint string) *Schema {
@@ -76,7 +84,8 @@ func (r *mapRegistry) Schema(t reflect.Type, allowRef bool, hint string) *Schema
 		return r.Schema(alias, allowRef, hint)
 	}
 
-	getsRef := t.Kind() == reflect.Struct
+	oneOf := r.oneOfs[t]
+	getsRef := t.Kind() == reflect.Struct || oneOf != nil
 	if t == timeType {
 		// Special case: time.Time is always a string.
 		getsRef = false
@@ -110,7 +119,12 @@ func (r *mapRegistry) Schema(t reflect.Type, allowRef bool, hint string) *Schema
 		r.types[name] = t
 		r.seen[t] = true
 	}
-	s := SchemaFromType(r, origType)
+	var s *Schema
+	if oneOf != nil {
+		s = r.oneOfSchema(oneOf)
+	} else {
+		s = SchemaFromType(r, origType)
+	}
 	if getsRef {
 		r.schemas[name] = s
 	}
@@ -149,6 +163,56 @@ func (r *mapRegistry) RegisterTypeAlias(t reflect.Type, alias reflect.Type) {
 	r.aliases[t] = alias
 }
 
+// RegisterOneOf makes the schema generator use a `oneOf` of the variant types
+// with a discriminator for the interface type `t`. Each variant must be a
+// struct, or pointer to a struct, with the discriminator property. See also
+// the `huma.RegisterOneOf` generic helper.
+func (r *mapRegistry) RegisterOneOf(t reflect.Type, property string, variants map[string]reflect.Type) {
+	if t.Kind() != reflect.Interface {
+		panic(fmt.Errorf("oneOf type must be an interface: %s", t))
+	}
+	for value, vt := range variants {
+		if vt == nil || deref(vt).Kind() != reflect.Struct || !vt.Implements(t) {
+			panic(fmt.Errorf("oneOf variant %s for %s must be a struct implementing %s", value, property, t))
+		}
+	}
+	r.oneOfs[t] = &oneOfInfo{property, variants}
+}
+
+// oneOfSchema generates the `oneOf` schema with a discriminator mapping for
+// the registered interface type.
+func (r *mapRegistry) oneOfSchema(info *oneOfInfo) *Schema {
+	values := make([]string, 0, len(info.variants))
+	for value := range info.variants {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	s := &Schema{
+		Discriminator: &Discriminator{
+			PropertyName: info.property,
+			Mapping:      map[string]string{},
+		},
+		oneOfTypes: info.variants,
+	}
+	seen := map[string]bool{}
+	for _, value := range values {
+		vt := info.variants[value]
+		ref := r.Schema(vt, true, "")
+		if variant := r.SchemaFromRef(ref.Ref); variant == nil || variant.Properties[info.property] == nil {
+			panic(fmt.Errorf("oneOf variant %s is missing discriminator property %s", vt, info.property))
+		}
+		if !seen[ref.Ref] {
+			// Multiple values may map to the same variant type.
+			seen[ref.Ref] = true
+			s.OneOf = append(s.OneOf, ref)
+		}
+		s.Discriminator.Mapping[value] = ref.Ref
+	}
+	s.PrecomputeMessages()
+	return s
+}
+
 // NewMapRegistry creates a new registry that stores schemas in a map and
 // returns references to them using the given prefix.
 func NewMapRegistry(prefix string, namer func(t reflect.Type, hint string) string) Registry {
@@ -158,6 +222,7 @@ func NewMapRegistry(prefix string, namer func(t reflect.Type, hint string) strin
 		types:   map[string]reflect.Type{},
 		seen:    map[reflect.Type]bool{},
 		aliases: map[reflect.Type]reflect.Type{},
+		oneOfs:  map[reflect.Type]*oneOfInfo{},
 		namer:   namer,
 	}
 }
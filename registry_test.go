@@ -73,3 +73,11 @@ func TestSchemaAlias(t *testing.T) {
 	schemaWithString := registry.Schema(reflect.TypeOf(StructWithString{}), false, "")
 	assert.Equal(t, schemaWithString, schemaWithContainer)
 }
+
+func TestRegisterOneOfUnsupported(t *testing.T) {
+	// Wrapping the registry hides its optional methods.
+	registry := struct{ Registry }{NewMapRegistry("#/components/schemas", DefaultSchemaNamer)}
+	assert.Panics(t, func() {
+		RegisterOneOf[any](registry, "type", map[string]any{"s": S{}})
+	})
+}
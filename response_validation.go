@@ -0,0 +1,153 @@
+package huma
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// ResponseValidationMode controls whether responses are validated against
+// the operation's documented response schemas, and what happens when they
+// don't match.
+type ResponseValidationMode int
+
+const (
+	// ResponseValidationDefault uses the API's `Config.ResponseValidation`
+	// setting for operations, and disables response validation for the API.
+	ResponseValidationDefault ResponseValidationMode = iota
+
+	// ResponseValidationOff disables response validation.
+	ResponseValidationOff
+
+	// ResponseValidationReport validates responses and reports any errors via
+	// `Config.OnResponseValidationError` or logs them. The response is still
+	// sent as-is.
+	ResponseValidationReport
+
+	// ResponseValidationError validates responses and reports any errors like
+	// `ResponseValidationReport`, then replaces the response with a 500
+	// Internal Server Error describing what was wrong.
+	ResponseValidationError
+)
+
+// responseValidator validates handler output against an operation's
+// documented responses.
+type responseValidator struct {
+	registry Registry
+	op       *Operation
+	mode     ResponseValidationMode
+}
+
+// response returns the documented response for the status code, falling back
+// to the `default` response if present.
+func (rv *responseValidator) response(status int) *Response {
+	if resp := rv.op.Responses[strconv.Itoa(status)]; resp != nil {
+		return resp
+	}
+	return rv.op.Responses["default"]
+}
+
+// validateHeader validates a response header value against its documented
+// schema, if any. Values are validated as they are written, so headers which
+// are not sent are skipped.
+func (rv *responseValidator) validateHeader(pb *PathBuffer, res *ValidateResult, status int, info *headerInfo, f reflect.Value) {
+	resp := rv.response(status)
+	if resp == nil || resp.Headers[info.Name] == nil || resp.Headers[info.Name].Schema == nil {
+		return
+	}
+
+	var value any
+	writeHeader(func(_, v string) { value = v }, info, f)
+	if value == nil {
+		return
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		// Validate the typed value rather than its string representation.
+		value = f.Interface()
+	}
+
+	pb.Reset()
+	pb.Push("header")
+	pb.Push(info.Name)
+	rv.validate(pb, res, resp.Headers[info.Name].Schema, value)
+}
+
+// validateBody validates the response status and body against the documented
+// response for the negotiated content type.
+func (rv *responseValidator) validateBody(pb *PathBuffer, res *ValidateResult, status int, ct string, body any) {
+	pb.Reset()
+	pb.Push("body")
+	resp := rv.response(status)
+	if resp == nil {
+		res.Add(pb, status, "undocumented response status "+strconv.Itoa(status))
+		return
+	}
+
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		return
+	}
+
+	var mt *MediaType
+	if resp.Content != nil {
+		if mt = resp.Content[ct]; mt == nil {
+			mt = resp.Content["application/json"]
+		}
+	}
+	if mt == nil || mt.Schema == nil {
+		return
+	}
+	rv.validate(pb, res, mt.Schema, body)
+}
+
+// validate converts the Go value into its generic JSON representation, which
+// the validator understands, then validates it against the schema.
+func (rv *responseValidator) validate(pb *PathBuffer, res *ValidateResult, s *Schema, value any) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		res.Add(pb, value, "cannot marshal value: "+err.Error())
+		return
+	}
+	var parsed any
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		res.Add(pb, value, "cannot unmarshal value: "+err.Error())
+		return
+	}
+	Validate(rv.registry, s, pb, ModeReadFromServer, parsed, res)
+}
+
+// check reports any response validation errors and returns whether the
+// response should still be written. In `ResponseValidationError` mode the
+// response is replaced by an error instead.
+func (rv *responseValidator) check(api API, ctx Context, status int, res *ValidateResult) bool {
+	if len(res.Errors) == 0 {
+		return true
+	}
+
+	details := make([]*ErrorDetail, 0, len(res.Errors))
+	for _, err := range res.Errors {
+		if d, ok := err.(*ErrorDetail); ok {
+			details = append(details, d)
+		} else {
+			details = append(details, &ErrorDetail{Message: err.Error()})
+		}
+	}
+
+	if hook := configOf(api).OnResponseValidationError; hook != nil {
+		hook(ctx, status, details)
+	} else {
+		for _, d := range details {
+			log.Printf("response validation failed for %s %s %d: %s", rv.op.Method, rv.op.Path, status, d.Error())
+		}
+	}
+
+	if rv.mode == ResponseValidationError {
+		WriteErr(api, ctx, http.StatusInternalServerError, "response validation failed", res.Errors...)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,297 @@
+package huma
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RouteErrorHandler wraps the router's HTTP handler to send consistent errors
+// for requests which don't match any operation, for any router built on
+// `net/http`. Requests for a registered path with a method that has no
+// operation get a 405 Method Not Allowed error with an `Allow` header listing
+// the registered methods, and requests the router can't find get a 404 Not
+// Found error, both using the API's error model and content negotiation.
+//
+//	router := chi.NewMux()
+//	api := humachi.New(router, huma.DefaultConfig("My API", "1.0.0"))
+//	http.ListenAndServe(":8888", huma.RouteErrorHandler(api, router))
+//
+// Routes registered directly on the router, like the generated docs, keep
+// working as the router's own 404 and 405 responses are only replaced once
+// it has tried to handle the request. Error responses written by operations
+// are never replaced.
+func RouteErrorHandler(api API, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := &routeMatch{}
+		iw := &interceptWriter{ResponseWriter: w, match: m}
+		next.ServeHTTP(iw, r.WithContext(context.WithValue(r.Context(), routeMatchKey{}, m)))
+		if iw.intercepted {
+			h := w.Header()
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			h.Del("X-Content-Type-Options")
+			writeRouteError(api, &routeContext{r: r, w: w}, iw.status == http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// WriteRouteError writes the error for a request which the router could not
+// match to any route: a 405 Method Not Allowed error with an `Allow` header if
+// the request path matches a registered path, otherwise a 404 Not Found error.
+// It is meant for routers which are not built on `net/http`, like Fiber, and
+// should only be called once the router has failed to find a route. See
+// `RouteErrorHandler` for routers built on `net/http`.
+func WriteRouteError(api API, ctx Context) {
+	writeRouteError(api, ctx, false)
+}
+
+// writeRouteError writes a 405 or 404 error for an unmatched request. If the
+// router reported a 405 for a method which is registered, e.g. because it
+// doesn't send `HEAD` requests to `GET` handlers, that method is left out.
+func writeRouteError(api API, ctx Context, methodNotAllowed bool) {
+	u := ctx.URL()
+	if allow, found := allowedMethods(api, u.Path); found {
+		if !slicesContains(allow, ctx.Method()) {
+			writeMethodNotAllowed(api, ctx, allow)
+			return
+		}
+		if methodNotAllowed {
+			others := []string{}
+			for _, method := range allow {
+				if method != ctx.Method() {
+					others = append(others, method)
+				}
+			}
+			writeMethodNotAllowed(api, ctx, others)
+			return
+		}
+	}
+	WriteErr(api, ctx, http.StatusNotFound, "no operation found for path "+u.Path)
+}
+
+// writeMethodNotAllowed writes a 405 Method Not Allowed error with an `Allow`
+// header listing the allowed methods.
+func writeMethodNotAllowed(api API, ctx Context, allow []string) {
+	ctx.SetHeader("Allow", strings.Join(allow, ", "))
+	WriteErr(api, ctx, http.StatusMethodNotAllowed, "method "+ctx.Method()+" not allowed, expected one of "+strings.Join(allow, ", "))
+}
+
+// allowedMethods returns the methods registered for all paths which match the
+// request path, including those of hidden operations, and whether any paths
+// matched. It scans all paths, so it is only used once the router has failed
+// to find a route.
+func allowedMethods(api API, path string) ([]string, bool) {
+	oapi := api.OpenAPI()
+	methods := []string{}
+	found := false
+	for template, item := range oapi.Paths {
+		if matchPath(template, path) {
+			found = true
+			methods = append(methods, pathMethods(item)...)
+		}
+	}
+	for template, registered := range oapi.routes {
+		if matchPath(template, path) {
+			found = true
+			methods = append(methods, registered...)
+		}
+	}
+	if slicesContains(methods, http.MethodGet) {
+		// Most routers answer `HEAD` requests with the `GET` handler.
+		methods = append(methods, http.MethodHead)
+	}
+	return sortMethods(methods), found
+}
+
+// routeMatchKey is the context key for the route match. It is zero-size so
+// lookups don't allocate.
+type routeMatchKey struct{}
+
+// routeMatch records whether the router passed the request to an operation.
+type routeMatch struct {
+	matched bool
+}
+
+// matchRoute wraps the handler to tell `RouteErrorHandler` that the router
+// found a route for the request, so that errors written by the operation are
+// not replaced.
+func matchRoute(handle func(Context)) func(Context) {
+	return func(ctx Context) {
+		if m, ok := ctx.Context().Value(routeMatchKey{}).(*routeMatch); ok {
+			m.matched = true
+		}
+		handle(ctx)
+	}
+}
+
+// matchPath returns whether the request path matches the OpenAPI path
+// template, where each `{param}` matches a single non-empty path segment and
+// a Go-style `{param...}` wildcard matches the rest of the path.
+func matchPath(template, path string) bool {
+	tparts := strings.Split(strings.Trim(template, "/"), "/")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, tpart := range tparts {
+		if strings.HasPrefix(tpart, "{") && strings.HasSuffix(tpart, "...}") {
+			return i < len(parts)
+		}
+		if i >= len(parts) {
+			return false
+		}
+		if strings.Contains(tpart, "{") {
+			// Params may have a prefix or suffix, like `{id}.json`.
+			prefix, _, _ := strings.Cut(tpart, "{")
+			suffix := tpart[strings.LastIndex(tpart, "}")+1:]
+			if len(parts[i]) <= len(prefix)+len(suffix) || !strings.HasPrefix(parts[i], prefix) || !strings.HasSuffix(parts[i], suffix) {
+				return false
+			}
+			continue
+		}
+		if tpart != parts[i] {
+			return false
+		}
+	}
+	return len(tparts) == len(parts)
+}
+
+// interceptWriter discards the router's 404 and 405 responses so they can be
+// replaced, passing all other responses through, including those written by
+// operations.
+type interceptWriter struct {
+	http.ResponseWriter
+	match       *routeMatch
+	wroteHeader bool
+	intercepted bool
+	status      int
+}
+
+func (w *interceptWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if (status == http.StatusNotFound || status == http.StatusMethodNotAllowed) && !w.match.matched {
+		w.intercepted = true
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *interceptWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.intercepted {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *interceptWriter) Flush() {
+	if w.intercepted {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *interceptWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap supports `http.ResponseController` for the underlying writer.
+func (w *interceptWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// routeContext is a minimal `huma.Context` used to write errors for requests
+// which did not match an operation.
+type routeContext struct {
+	r      *http.Request
+	w      http.ResponseWriter
+	status int
+}
+
+func (c *routeContext) Operation() *Operation {
+	return nil
+}
+
+func (c *routeContext) Context() context.Context {
+	return c.r.Context()
+}
+
+func (c *routeContext) Method() string {
+	return c.r.Method
+}
+
+func (c *routeContext) Host() string {
+	return c.r.Host
+}
+
+func (c *routeContext) URL() url.URL {
+	return *c.r.URL
+}
+
+func (c *routeContext) Param(name string) string {
+	return ""
+}
+
+func (c *routeContext) Query(name string) string {
+	return c.r.URL.Query().Get(name)
+}
+
+func (c *routeContext) Header(name string) string {
+	return c.r.Header.Get(name)
+}
+
+func (c *routeContext) EachHeader(cb func(name, value string)) {
+	for name, values := range c.r.Header {
+		for _, value := range values {
+			cb(name, value)
+		}
+	}
+}
+
+func (c *routeContext) BodyReader() io.Reader {
+	return c.r.Body
+}
+
+func (c *routeContext) GetMultipartForm() (*multipart.Form, error) {
+	return nil, http.ErrNotMultipart
+}
+
+func (c *routeContext) SetReadDeadline(deadline time.Time) error {
+	return http.NewResponseController(c.w).SetReadDeadline(deadline)
+}
+
+func (c *routeContext) SetStatus(code int) {
+	c.status = code
+	c.w.WriteHeader(code)
+}
+
+func (c *routeContext) Status() int {
+	return c.status
+}
+
+func (c *routeContext) AppendHeader(name, value string) {
+	c.w.Header().Add(name, value)
+}
+
+func (c *routeContext) SetHeader(name, value string) {
+	c.w.Header().Set(name, value)
+}
+
+func (c *routeContext) BodyWriter() io.Writer {
+	return c.w
+}
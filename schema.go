@@ -97,27 +97,36 @@ type Schema struct {
 	AllOf []*Schema `yaml:"allOf,omitempty"`
 	Not   *Schema   `yaml:"not,omitempty"`
 
+	// Discriminator is an OpenAPI extension to JSON Schema which selects one
+	// of the `OneOf` schemas based on the value of a property.
+	Discriminator *Discriminator `yaml:"discriminator,omitempty"`
+
 	patternRe     *regexp.Regexp  `yaml:"-"`
 	requiredMap   map[string]bool `yaml:"-"`
 	propertyNames []string        `yaml:"-"`
 
+	// oneOfTypes maps discriminator values to Go types for decoding.
+	oneOfTypes map[string]reflect.Type `yaml:"-"`
+
 	// Precomputed validation messages. These prevent allocations during
 	// validation and are known at schema creation time.
-	msgEnum              string                       `yaml:"-"`
-	msgMinimum           string                       `yaml:"-"`
-	msgExclusiveMinimum  string                       `yaml:"-"`
-	msgMaximum           string                       `yaml:"-"`
-	msgExclusiveMaximum  string                       `yaml:"-"`
-	msgMultipleOf        string                       `yaml:"-"`
-	msgMinLength         string                       `yaml:"-"`
-	msgMaxLength         string                       `yaml:"-"`
-	msgPattern           string                       `yaml:"-"`
-	msgMinItems          string                       `yaml:"-"`
-	msgMaxItems          string                       `yaml:"-"`
-	msgMinProperties     string                       `yaml:"-"`
-	msgMaxProperties     string                       `yaml:"-"`
-	msgRequired          map[string]string            `yaml:"-"`
-	msgDependentRequired map[string]map[string]string `yaml:"-"`
+	msgEnum                  string                       `yaml:"-"`
+	msgMinimum               string                       `yaml:"-"`
+	msgExclusiveMinimum      string                       `yaml:"-"`
+	msgMaximum               string                       `yaml:"-"`
+	msgExclusiveMaximum      string                       `yaml:"-"`
+	msgMultipleOf            string                       `yaml:"-"`
+	msgMinLength             string                       `yaml:"-"`
+	msgMaxLength             string                       `yaml:"-"`
+	msgPattern               string                       `yaml:"-"`
+	msgMinItems              string                       `yaml:"-"`
+	msgMaxItems              string                       `yaml:"-"`
+	msgMinProperties         string                       `yaml:"-"`
+	msgMaxProperties         string                       `yaml:"-"`
+	msgRequired              map[string]string            `yaml:"-"`
+	msgDependentRequired     map[string]map[string]string `yaml:"-"`
+	msgDiscriminator         string                       `yaml:"-"`
+	msgDiscriminatorRequired string                       `yaml:"-"`
 }
 
 // MarshalJSON marshals the schema into JSON, respecting the `Extensions` map
@@ -163,6 +172,7 @@ func (s *Schema) MarshalJSON() ([]byte, error) {
 		{"anyOf", s.AnyOf, omitEmpty},
 		{"allOf", s.AllOf, omitEmpty},
 		{"not", s.Not, omitEmpty},
+		{"discriminator", s.Discriminator, omitEmpty},
 	}, s.Extensions)
 }
 
@@ -237,6 +247,16 @@ func (s *Schema) PrecomputeMessages() {
 		}
 	}
 
+	if s.Discriminator != nil {
+		values := make([]string, 0, len(s.Discriminator.Mapping))
+		for value := range s.Discriminator.Mapping {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		s.msgDiscriminator = "expected discriminator " + s.Discriminator.PropertyName + " to be one of \"" + strings.Join(values, ", ") + "\""
+		s.msgDiscriminatorRequired = "expected required discriminator property " + s.Discriminator.PropertyName + " to be present"
+	}
+
 	if s.propertyNames == nil {
 		s.propertyNames = make([]string, 0, len(s.Properties))
 		for name := range s.Properties {
@@ -0,0 +1,321 @@
+package huma
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Credentials are read from a request based on the type of the security
+// scheme they are used with:
+//
+//   - `apiKey`: the key from the header, query param, or cookie as `Token`.
+//   - `http` with the `basic` scheme: the `Username` and `Password`.
+//   - `http` with other schemes like `bearer`: the value after the scheme in
+//     the `Authorization` header as `Token`.
+//   - `oauth2` and `openIdConnect`: the bearer token as `Token`.
+type Credentials struct {
+	// Scheme is the name of the security scheme in the OpenAPI components.
+	Scheme string
+
+	// Username from HTTP basic auth.
+	Username string
+
+	// Password from HTTP basic auth.
+	Password string
+
+	// Token is the bearer token, API key, or other credential.
+	Token string
+}
+
+// Authenticator verifies the credentials for a security scheme and returns
+// the authenticated principal, e.g. a user or client, which handlers can get
+// with `GetPrincipal`. Returning an error rejects the credentials, and the
+// error message is included in the 401 Unauthorized response.
+//
+//	config.Authenticators = map[string]huma.Authenticator{
+//		"bearer": func(ctx huma.Context, creds huma.Credentials) (any, error) {
+//			user, err := verifyToken(creds.Token)
+//			if err != nil {
+//				return nil, errors.New("invalid token")
+//			}
+//			return user, nil
+//		},
+//	}
+type Authenticator func(ctx Context, creds Credentials) (any, error)
+
+type contextKey string
+
+var principalKey contextKey = "huma/principal"
+
+// GetPrincipal returns the principal returned by the `Authenticator` for the
+// request, or `nil` if the request was not authenticated. If a security
+// requirement has multiple schemes, the principal for the scheme whose name
+// sorts first is returned.
+func GetPrincipal(ctx context.Context) any {
+	return ctx.Value(principalKey)
+}
+
+// securityScheme is a security scheme used by an operation along with the
+// authenticator which verifies its credentials.
+type securityScheme struct {
+	name         string
+	scheme       *SecurityScheme
+	authenticate Authenticator
+	scopes       []string
+}
+
+// credentials reads the credentials for the scheme from the request. Returns
+// false if the request has none.
+func (s *securityScheme) credentials(ctx Context) (Credentials, bool) {
+	creds := Credentials{Scheme: s.name}
+	switch s.scheme.Type {
+	case "apiKey":
+		switch s.scheme.In {
+		case "header":
+			creds.Token = ctx.Header(s.scheme.Name)
+		case "query":
+			creds.Token = ctx.Query(s.scheme.Name)
+		case "cookie":
+			if c, err := ReadCookie(ctx, s.scheme.Name); err == nil {
+				creds.Token = c.Value
+			}
+		}
+		return creds, creds.Token != ""
+	case "http":
+		kind, value, _ := strings.Cut(ctx.Header("Authorization"), " ")
+		if !strings.EqualFold(kind, s.scheme.Scheme) {
+			return creds, false
+		}
+		value = strings.TrimSpace(value)
+		if strings.EqualFold(s.scheme.Scheme, "basic") {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return creds, false
+			}
+			creds.Username, creds.Password, _ = strings.Cut(string(decoded), ":")
+			return creds, true
+		}
+		creds.Token = value
+		return creds, value != ""
+	case "oauth2", "openIdConnect":
+		kind, value, _ := strings.Cut(ctx.Header("Authorization"), " ")
+		if !strings.EqualFold(kind, "bearer") {
+			return creds, false
+		}
+		creds.Token = strings.TrimSpace(value)
+		return creds, creds.Token != ""
+	}
+	return creds, false
+}
+
+// challenge returns the `WWW-Authenticate` challenge for the scheme, if any.
+func (s *securityScheme) challenge(realm string) string {
+	switch s.scheme.Type {
+	case "http":
+		if strings.EqualFold(s.scheme.Scheme, "basic") {
+			return `Basic realm="` + realm + `"`
+		}
+		if strings.EqualFold(s.scheme.Scheme, "bearer") {
+			return "Bearer"
+		}
+		return s.scheme.Scheme
+	case "oauth2", "openIdConnect":
+		return "Bearer"
+	}
+	return ""
+}
+
+// security enforces the security requirements of an operation. Each
+// requirement lists schemes which must all succeed, including holding any
+// listed scopes, and any one requirement succeeding allows the request.
+type security struct {
+	api          API
+	requirements [][]*securityScheme
+	optional     bool
+	challenges   []string
+}
+
+// newSecurity returns the security enforcer for the operation, or `nil` if
+// the API has no authenticators or the operation has no security
+// requirements. It panics if a required scheme is not defined or has no
+// authenticator.
+func newSecurity(api API, op *Operation) *security {
+	authenticators := configOf(api).Authenticators
+	if len(authenticators) == 0 {
+		return nil
+	}
+
+	oapi := api.OpenAPI()
+	requirements := op.Security
+	if requirements == nil {
+		// Operations use the API's requirements unless they set their own.
+		requirements = oapi.Security
+	}
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	realm := "API"
+	if oapi.Info != nil && oapi.Info.Title != "" {
+		realm = oapi.Info.Title
+	}
+
+	s := &security{api: api}
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			// An empty requirement makes authentication optional.
+			s.optional = true
+			continue
+		}
+		names := make([]string, 0, len(requirement))
+		for name := range requirement {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		schemes := make([]*securityScheme, 0, len(names))
+		for _, name := range names {
+			var scheme *SecurityScheme
+			if oapi.Components != nil {
+				scheme = oapi.Components.SecuritySchemes[name]
+			}
+			if scheme == nil {
+				panic("unknown security scheme " + name + " for operation " + op.OperationID)
+			}
+			authenticate := authenticators[name]
+			if authenticate == nil {
+				panic("no authenticator for security scheme " + name)
+			}
+			ss := &securityScheme{name: name, scheme: scheme, authenticate: authenticate, scopes: requirement[name]}
+			if c := ss.challenge(realm); c != "" && !slicesContains(s.challenges, c) {
+				s.challenges = append(s.challenges, c)
+			}
+			schemes = append(schemes, ss)
+		}
+		s.requirements = append(s.requirements, schemes)
+	}
+	return s
+}
+
+// required returns whether requests must be authenticated.
+func (s *security) required() bool {
+	return s != nil && !s.optional
+}
+
+// scoped returns whether any requirement needs scopes.
+func (s *security) scoped() bool {
+	if s == nil {
+		return false
+	}
+	for _, schemes := range s.requirements {
+		for _, scheme := range schemes {
+			if len(scheme.scopes) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handler wraps the handler to authenticate and authorize requests before
+// calling it. The principal is added to the request context.
+func (s *security) handler(next func(Context)) func(Context) {
+	if s == nil {
+		return next
+	}
+	return func(ctx Context) {
+		var errs, forbidden []error
+		var missing []string
+		presented := false
+	requirements:
+		for _, schemes := range s.requirements {
+			var principal any
+			for _, scheme := range schemes {
+				creds, ok := scheme.credentials(ctx)
+				if !ok {
+					continue requirements
+				}
+				presented = true
+				p, err := scheme.authenticate(ctx, creds)
+				if err != nil {
+					errs = append(errs, &ErrorDetail{
+						Message:  err.Error(),
+						Location: "security." + scheme.name,
+					})
+					continue requirements
+				}
+				if m := missingScopes(p, scheme.scopes); len(m) > 0 {
+					for _, scope := range m {
+						forbidden = append(forbidden, &ErrorDetail{
+							Message:  "missing scope",
+							Location: "security." + scheme.name,
+							Value:    scope,
+						})
+						if !slicesContains(missing, scope) {
+							missing = append(missing, scope)
+						}
+					}
+					continue requirements
+				}
+				if principal == nil {
+					principal = p
+				}
+			}
+			if principal != nil {
+				ctx = WithValue(ctx, principalKey, principal)
+			}
+			next(ctx)
+			return
+		}
+
+		if s.optional && !presented {
+			next(ctx)
+			return
+		}
+
+		if len(forbidden) > 0 {
+			// The client is authenticated, but is not allowed to do this.
+			for _, c := range s.challenges {
+				if c == "Bearer" {
+					ctx.AppendHeader("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(missing, " ")+`"`)
+				}
+			}
+			WriteErr(s.api, ctx, http.StatusForbidden, "insufficient scope, missing "+strings.Join(missing, ", "), forbidden...)
+			return
+		}
+
+		for _, c := range s.challenges {
+			ctx.AppendHeader("WWW-Authenticate", c)
+		}
+		msg := "authentication required"
+		if presented {
+			msg = "invalid credentials"
+		}
+		WriteErr(s.api, ctx, http.StatusUnauthorized, msg, errs...)
+	}
+}
+
+// ScopedPrincipal is implemented by principals which hold scopes, like the
+// claims from an OAuth 2.0 access token. When a security requirement lists
+// scopes, e.g. `{"oauth2": ["orders:write"]}`, the principal returned by the
+// `Authenticator` must hold all of them or the request gets a 403 Forbidden
+// error listing the missing scopes.
+type ScopedPrincipal interface {
+	HasScope(scope string) bool
+}
+
+// missingScopes returns the required scopes which the principal does not
+// hold. Principals which don't implement `ScopedPrincipal` hold no scopes.
+func missingScopes(principal any, scopes []string) []string {
+	var missing []string
+	sp, _ := principal.(ScopedPrincipal)
+	for _, scope := range scopes {
+		if sp == nil || !sp.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,59 @@
+package huma
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+var readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+
+// streamBodyReader streams the request body to a handler via an `RawBody
+// io.Reader` input field without buffering it. Each read extends the read
+// deadline so the timeout applies to idle time rather than the whole upload,
+// and reads fail once more than the maximum number of bytes have been read.
+// Errors are returned as `StatusError` values so that handlers can return
+// them directly to send the appropriate response.
+type streamBodyReader struct {
+	ctx      Context
+	reader   io.Reader
+	timeout  time.Duration
+	maxBytes int64
+	read     int64
+}
+
+func (r *streamBodyReader) Read(p []byte) (int, error) {
+	if r.timeout > 0 {
+		r.ctx.SetReadDeadline(time.Now().Add(r.timeout))
+	}
+
+	if r.maxBytes > 0 {
+		if r.read > r.maxBytes {
+			return 0, r.errTooLarge()
+		}
+		if remaining := r.maxBytes - r.read + 1; int64(len(p)) > remaining {
+			// Read at most one byte past the limit to detect too-large bodies.
+			p = p[:remaining]
+		}
+	}
+
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.maxBytes > 0 && r.read > r.maxBytes {
+		return n - int(r.read-r.maxBytes), r.errTooLarge()
+	}
+
+	var ne net.Error
+	if err != nil && errors.As(err, &ne) && ne.Timeout() {
+		return n, NewError(http.StatusRequestTimeout, "request body read timeout")
+	}
+	return n, err
+}
+
+func (r *streamBodyReader) errTooLarge() error {
+	return NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is too large limit=%d bytes", r.maxBytes))
+}
@@ -0,0 +1,310 @@
+package huma
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Attribute keys set on spans by Huma. Names follow the OpenTelemetry
+// semantic conventions where one exists.
+const (
+	// AttrOperationID is the operation ID, if any.
+	AttrOperationID = "huma.operation.id"
+
+	// AttrRoute is the operation's path template, e.g. `/items/{id}`.
+	AttrRoute = "http.route"
+
+	// AttrMethod is the HTTP method of the request.
+	AttrMethod = "http.request.method"
+
+	// AttrStatus is the HTTP status code of the response.
+	AttrStatus = "http.response.status_code"
+
+	// AttrErrorLocations is a `[]string` of the locations of request
+	// validation errors, e.g. `query.limit` or `body.items[0].name`.
+	AttrErrorLocations = "huma.error.locations"
+)
+
+// Attribute is a key/value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span is a timed unit of work started by a `Tracer`.
+type Span interface {
+	// SetAttributes adds or replaces attributes on the span.
+	SetAttributes(attrs ...Attribute)
+
+	// SetError marks the span as failed.
+	SetError(err error)
+
+	// End finishes the span. It is called exactly once.
+	End()
+}
+
+// Tracer starts spans for requests handled by operations, for example to
+// bridge them to OpenTelemetry or another tracing backend. Each request gets
+// a span named like `GET /items/{id}`, with child spans for each phase of
+// request handling:
+//
+//   - `huma.params` parses and validates parameters
+//   - `huma.body.read` reads the request body
+//   - `huma.body.validate` unmarshals and validates the request body
+//   - `huma.resolve` runs resolvers
+//   - `huma.handler` calls the operation handler
+//   - `huma.transform` runs response transformers
+//   - `huma.marshal` marshals and writes the response body
+//
+// Phases which do not apply to a request are skipped. The context passed to
+// the handler is the context of the `huma.handler` span, so spans started by
+// the handler become its children.
+//
+// If the request has a valid W3C `traceparent` header then the context passed
+// to `Start` for the request span contains it, see `GetTraceParent`.
+// Implementations should use it as the parent span, and should store their
+// own span's trace parent in the returned context with `WithTraceParent` so
+// it can be propagated to other services.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// TraceParent is a W3C Trace Context `traceparent` value, see
+// https://www.w3.org/TR/trace-context/#traceparent-header.
+type TraceParent struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// ParseTraceParent parses a `traceparent` header value. It returns false if
+// the value is not valid.
+func ParseTraceParent(value string) (TraceParent, bool) {
+	var tp TraceParent
+
+	// version "-" trace-id "-" parent-id "-" trace-flags
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return tp, false
+	}
+	version, ok := decodeHex(value[0:2], 1)
+	if !ok || version[0] == 0xff {
+		return tp, false
+	}
+	if len(value) > 55 && (version[0] == 0 || value[55] != '-') {
+		// Version 00 has no extra fields, and future versions must separate
+		// them with a dash.
+		return tp, false
+	}
+	traceID, ok := decodeHex(value[3:35], 16)
+	if !ok {
+		return tp, false
+	}
+	spanID, ok := decodeHex(value[36:52], 8)
+	if !ok {
+		return tp, false
+	}
+	flags, ok := decodeHex(value[53:55], 1)
+	if !ok {
+		return tp, false
+	}
+	copy(tp.TraceID[:], traceID)
+	copy(tp.SpanID[:], spanID)
+	tp.Flags = flags[0]
+	if tp.TraceID == [16]byte{} || tp.SpanID == [8]byte{} {
+		return tp, false
+	}
+	return tp, true
+}
+
+// decodeHex decodes lowercase hex of exactly n bytes.
+func decodeHex(s string, n int) ([]byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return nil, false
+		}
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil && len(b) == n
+}
+
+// NewTraceParent creates a sampled trace parent with a random trace ID and
+// span ID, for starting a new trace.
+func NewTraceParent() TraceParent {
+	tp := TraceParent{Flags: 1}
+	rand.Read(tp.TraceID[:])
+	return tp.Child()
+}
+
+// Child returns a trace parent for a new span in the same trace.
+func (tp TraceParent) Child() TraceParent {
+	rand.Read(tp.SpanID[:])
+	return tp
+}
+
+// Sampled returns whether the caller may have recorded the trace.
+func (tp TraceParent) Sampled() bool {
+	return tp.Flags&1 == 1
+}
+
+// String returns the `traceparent` header value.
+func (tp TraceParent) String() string {
+	return fmt.Sprintf("00-%x-%x-%02x", tp.TraceID, tp.SpanID, tp.Flags)
+}
+
+// traceParentKey is the context key for the trace parent. It is zero-size so
+// lookups don't allocate.
+type traceParentKey struct{}
+
+// WithTraceParent returns a copy of the context with the trace parent.
+func WithTraceParent(ctx context.Context, tp TraceParent) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, tp)
+}
+
+// GetTraceParent returns the trace parent from the context, if any. Set it as
+// the `traceparent` header on outgoing requests to continue the trace.
+//
+//	if tp, ok := huma.GetTraceParent(ctx); ok {
+//		req.Header.Set("traceparent", tp.String())
+//	}
+func GetTraceParent(ctx context.Context) (TraceParent, bool) {
+	tp, ok := ctx.Value(traceParentKey{}).(TraceParent)
+	return tp, ok
+}
+
+// traceKey is the context key for the request trace.
+type traceKey struct{}
+
+// requestTrace tracks the request span and the current phase span.
+type requestTrace struct {
+	tracer Tracer
+	root   Span
+	span   Span
+	ctx    context.Context
+}
+
+// traceHandler wraps the handler to start a span for each request.
+func traceHandler(tracer Tracer, op *Operation, handle func(Context)) func(Context) {
+	return func(ctx Context) {
+		parent := ctx.Context()
+		if tp, ok := ParseTraceParent(ctx.Header("traceparent")); ok {
+			parent = WithTraceParent(parent, tp)
+		}
+		attrs := []Attribute{
+			{Key: AttrRoute, Value: op.Path},
+			{Key: AttrMethod, Value: ctx.Method()},
+		}
+		if op.OperationID != "" {
+			attrs = append(attrs, Attribute{Key: AttrOperationID, Value: op.OperationID})
+		}
+
+		t := &requestTrace{tracer: tracer}
+		parent, t.root = tracer.Start(parent, ctx.Method()+" "+op.Path, attrs...)
+		tc := &traceContext{humaContext: WithContext(ctx, context.WithValue(parent, traceKey{}, t))}
+		defer func() {
+			v := recover()
+			status := tc.status
+			if v != nil {
+				// Without `Config.RecoverPanics` the panic reaches the router,
+				// which usually aborts the response. Record it as a failure
+				// before passing it on.
+				if status == 0 {
+					status = http.StatusInternalServerError
+				}
+				t.fail(&PanicError{Operation: op, Value: v, Stack: debug.Stack()})
+			} else if status == 0 {
+				status = http.StatusOK
+			}
+			t.end()
+			t.root.SetAttributes(Attribute{Key: AttrStatus, Value: status})
+			if status >= 500 && v == nil {
+				t.root.SetError(errors.New(http.StatusText(status)))
+			}
+			t.root.End()
+			if v != nil {
+				panic(v)
+			}
+		}()
+		handle(tc)
+	}
+}
+
+// traceFrom returns the request trace for the context, if any. Skip it when
+// tracing is disabled, as the lookup walks the whole context chain.
+func traceFrom(ctx Context) *requestTrace {
+	t, _ := ctx.Context().Value(traceKey{}).(*requestTrace)
+	return t
+}
+
+// phase ends the current phase span, if any, and starts a new one as a child
+// of the context. It returns the context of the new span.
+func (t *requestTrace) phase(ctx context.Context, name string) context.Context {
+	if t == nil {
+		return ctx
+	}
+	t.end()
+	t.ctx, t.span = t.tracer.Start(ctx, name)
+	return t.ctx
+}
+
+// end ends the current phase span, if any.
+func (t *requestTrace) end() {
+	if t == nil || t.span == nil {
+		return
+	}
+	t.span.End()
+	t.span = nil
+	t.ctx = nil
+}
+
+// fail marks the current phase span and the request span as failed.
+func (t *requestTrace) fail(err error) {
+	if t == nil {
+		return
+	}
+	if t.span != nil {
+		t.span.SetError(err)
+	}
+	t.root.SetError(err)
+}
+
+// invalid records the locations of validation errors and marks the spans as
+// failed.
+func (t *requestTrace) invalid(errs []error) {
+	if t == nil {
+		return
+	}
+	locations := make([]string, 0, len(errs))
+	for _, err := range errs {
+		var ed ErrorDetailer
+		if errors.As(err, &ed) {
+			locations = append(locations, ed.ErrorDetail().Location)
+		}
+	}
+	attr := Attribute{Key: AttrErrorLocations, Value: locations}
+	if t.span != nil {
+		t.span.SetAttributes(attr)
+	}
+	t.root.SetAttributes(attr)
+	t.fail(errors.New("validation failed"))
+}
+
+// traceContext records the response status for the request span.
+type traceContext struct {
+	humaContext
+	status int
+}
+
+func (c *traceContext) SetStatus(code int) {
+	c.status = code
+	c.humaContext.SetStatus(code)
+}
+
+// Unwrap returns the wrapped context, see `huma.GetRemoteAddr`.
+func (c *traceContext) Unwrap() Context {
+	return c.humaContext
+}
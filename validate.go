@@ -277,6 +277,47 @@ func validateOneOf(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v
 	}
 }
 
+// validateDiscriminator validates the value against the `oneOf` schema
+// selected by its discriminator property, which gives much clearer errors
+// than trying each schema in turn.
+func validateDiscriminator(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any, res *ValidateResult) {
+	name := s.Discriminator.PropertyName
+	var value any
+	var ok bool
+	switch m := v.(type) {
+	case map[string]any:
+		value, ok = m[name]
+	case map[any]any:
+		value, ok = m[name]
+	default:
+		res.Add(path, v, "expected object")
+		return
+	}
+
+	if !ok {
+		res.Add(path, v, s.msgDiscriminatorRequired)
+		return
+	}
+
+	str, _ := value.(string)
+	ref, ok := s.Discriminator.Mapping[str]
+	if !ok {
+		path.Push(name)
+		res.Add(path, value, s.msgDiscriminator)
+		path.Pop()
+		return
+	}
+
+	sub := r.SchemaFromRef(ref)
+	if sub == nil {
+		// The mapping may use schema names rather than references, so fall back
+		// to trying each schema.
+		validateOneOf(r, s, path, mode, v, res)
+		return
+	}
+	Validate(r, sub, path, mode, v, res)
+}
+
 func validateAnyOf(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any, res *ValidateResult) {
 	matches := 0
 	subRes := &ValidateResult{}
@@ -315,7 +356,9 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 		s = r.SchemaFromRef(s.Ref)
 	}
 
-	if s.OneOf != nil {
+	if s.Discriminator != nil && len(s.Discriminator.Mapping) > 0 {
+		validateDiscriminator(r, s, path, mode, v, res)
+	} else if s.OneOf != nil {
 		validateOneOf(r, s, path, mode, v, res)
 	}
 